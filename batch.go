@@ -0,0 +1,153 @@
+package chring
+
+import "sort"
+
+// ChangeSet is a batch of membership changes for Ring.Apply. Changes are applied in the order they were added to the set.
+type ChangeSet struct {
+	changes []change
+}
+
+// change is a single change in a ChangeSet
+type change struct {
+	op     string
+	id     string
+	weight float64
+}
+
+// Add adds a node to the set, placed like Ring.Add
+func (cs *ChangeSet) Add(id string) {
+	cs.changes = append(cs.changes, change{op: opAdd, id: id})
+}
+
+// AddWeighted adds a weighted node to the set, placed like Ring.AddWeighted
+func (cs *ChangeSet) AddWeighted(id string, weight float64) {
+	cs.changes = append(cs.changes, change{op: opWeighted, id: id, weight: weight})
+}
+
+// SetWeight adds a weight change to the set, see Ring.SetWeight
+func (cs *ChangeSet) SetWeight(id string, weight float64) {
+	cs.changes = append(cs.changes, change{op: opSetWeight, id: id, weight: weight})
+}
+
+// Remove adds the removal of a node to the set
+func (cs *ChangeSet) Remove(id string) {
+	cs.changes = append(cs.changes, change{op: opRemove, id: id})
+}
+
+// Len returns the number of changes in the set
+func (cs *ChangeSet) Len() int {
+	return len(cs.changes)
+}
+
+// AddMany inserts the nodes into the hash ring at once, see Apply
+func (r *Ring) AddMany(ids ...string) []Migration {
+	var cs ChangeSet
+	for _, id := range ids {
+		cs.Add(id)
+	}
+	migrations, _ := r.Apply(cs) // adds cannot fail
+	return migrations
+}
+
+// Apply makes all the changes in the set at once: the ring is sorted once, and readers go straight from the view
+// before the changes to the view after them, never seeing the changes in between. It returns the ranges of the
+// hash space that changed owner, as Diff reports for the views before and after, and subscribers get them in a single
+// ChangesApplied event. Adding a node that is already in the ring is skipped as with Add. If any change fails, such
+// as removing a node that is not in the ring or an invalid weight, the error is returned and nothing is changed.
+func (r *Ring) Apply(cs ChangeSet) ([]Migration, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	// work on a copy of the members, so that the ring is untouched until every change has been checked
+	members := make(map[string]*member, len(r.members)+len(cs.changes))
+	for id, m := range r.members {
+		members[id] = m
+	}
+	placed := make(map[string]bool)   // members whose virtual nodes need placing
+	replaced := make(map[string]bool) // members whose current virtual nodes go away
+	for _, c := range cs.changes {
+		switch c.op {
+		case opAdd, opWeighted:
+			if _, ok := members[c.id]; ok {
+				continue
+			}
+			m := &member{ID: c.id, Replicas: r.replicas, Weight: 1}
+			if c.op == opWeighted {
+				if !validWeight(c.weight) {
+					return nil, ErrInvalidWeight
+				}
+				m.Replicas, m.Weight = weightedReplicas(r.replicas, c.weight), c.weight
+			}
+			members[c.id] = m
+			placed[c.id] = true
+		case opSetWeight:
+			if !validWeight(c.weight) {
+				return nil, ErrInvalidWeight
+			}
+			m, ok := members[c.id]
+			if !ok {
+				return nil, ErrNotFound
+			}
+			// views may still hold the member, so change a copy
+			reweighted := *m
+			reweighted.Weight, reweighted.Replicas = c.weight, weightedReplicas(r.replicas, c.weight)
+			members[c.id] = &reweighted
+			placed[c.id], replaced[c.id] = true, true
+		case opRemove:
+			if _, ok := members[c.id]; !ok {
+				return nil, ErrNotFound
+			}
+			delete(members, c.id)
+			delete(placed, c.id)
+			replaced[c.id] = true
+		}
+	}
+	if len(placed) == 0 && len(replaced) == 0 {
+		return nil, nil
+	}
+
+	old := r.current()
+	r.members = members
+	base := make(nodes, 0, len(old.nodes))
+	for _, n := range old.nodes {
+		if !replaced[n.ID] {
+			base = append(base, n)
+		}
+	}
+	ids := make([]string, 0, len(placed))
+	for id := range placed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	taken := make(map[uint32]bool)
+	ns := base
+	for _, id := range ids {
+		added := r.memberNodes(members[id], func(hashID uint32) bool { return taken[hashID] || base.occupied(hashID) })
+		for _, n := range added {
+			taken[n.HashID] = true
+		}
+		ns = append(ns, added...)
+	}
+	if r.collided() {
+		ns = r.layout()
+	} else {
+		sort.Sort(ns)
+	}
+
+	for id := range replaced {
+		if _, ok := members[id]; ok {
+			continue
+		}
+		if _, ok := r.states[id]; ok {
+			r.setState(id, Active)
+		}
+		r.forgetLoad(id)
+	}
+	r.publish(ns)
+
+	migrations := diff(old, r.current())
+	if len(r.subscribers) > 0 {
+		r.send(RingEvent{Type: ChangesApplied, Epoch: r.epoch, Migrations: migrations})
+	}
+	return migrations, nil
+}
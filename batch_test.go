@@ -0,0 +1,99 @@
+package chring_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestAddMany(t *testing.T) {
+	var ids []string
+	sequential := chring.NewRing(chring.WithReplicas(20))
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("node %d", i)
+		ids = append(ids, id)
+		sequential.Add(id)
+	}
+
+	batched := chring.NewRing(chring.WithReplicas(20))
+	batched.Add("node 0")
+	before := batched.Snapshot()
+	migrations := batched.AddMany(ids...)
+	after := batched.Snapshot()
+
+	if after.Checksum() != sequential.Snapshot().Checksum() {
+		t.Error("got a different layout than adding the nodes one at a time")
+	}
+	if after.Epoch() != before.Epoch()+1 {
+		t.Errorf("got epoch %d, want a single change from %d", after.Epoch(), before.Epoch())
+	}
+	if want := batched.Diff(before, after); !reflect.DeepEqual(migrations, want) {
+		t.Errorf("got %d migrations, want the %d of the combined diff", len(migrations), len(want))
+	}
+}
+
+func TestApply(t *testing.T) {
+	sequential := newSeededRing()
+	sequential.Add("node 5")
+	_ = sequential.AddWeighted("node 6", 2)
+	_ = sequential.SetWeight("node 1", 3)
+	_ = sequential.Remove("node 2")
+
+	batched := newSeededRing()
+	events, cancel := batched.Subscribe()
+	defer cancel()
+	var cs chring.ChangeSet
+	cs.Add("node 5")
+	cs.AddWeighted("node 6", 2)
+	cs.SetWeight("node 1", 3)
+	cs.Remove("node 2")
+	migrations, err := batched.Apply(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if batched.Snapshot().Checksum() != sequential.Snapshot().Checksum() {
+		t.Error("got a different layout than making the changes one at a time")
+	}
+	if w, _ := batched.Weight("node 1"); w != 3 {
+		t.Errorf("got weight %v, want 3", w)
+	}
+	event := <-events
+	if event.Type != chring.ChangesApplied || !reflect.DeepEqual(event.Migrations, migrations) {
+		t.Errorf("got %s event with %d migrations, want %s with %d", event.Type, len(event.Migrations), chring.ChangesApplied, len(migrations))
+	}
+	select {
+	case event := <-events:
+		t.Errorf("got another %s event, want one for the whole set", event.Type)
+	default:
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	ring := newSeededRing()
+	before := ring.Snapshot()
+
+	var cs chring.ChangeSet
+	cs.Add("node 5")
+	cs.Remove("node 1")
+	cs.Remove("node 1") // already removed by this set
+	if _, err := ring.Apply(cs); err != chring.ErrNotFound {
+		t.Errorf("got error %v, want %v", err, chring.ErrNotFound)
+	}
+
+	cs = chring.ChangeSet{}
+	cs.Add("node 5")
+	cs.SetWeight("node 1", -1)
+	if _, err := ring.Apply(cs); err != chring.ErrInvalidWeight {
+		t.Errorf("got error %v, want %v", err, chring.ErrInvalidWeight)
+	}
+
+	if ring.Snapshot() != before {
+		t.Error("got a new view after failed changes, want the ring untouched")
+	}
+	if len(ring.Nodes()) != len(NodeList) {
+		t.Errorf("got %d nodes, want %d", len(ring.Nodes()), len(NodeList))
+	}
+}
@@ -0,0 +1,129 @@
+package chring
+
+import (
+	"errors"
+	"math"
+)
+
+// DefaultLoadFactor is the bounded load factor used by GetWithLoad unless WithBoundedLoad says otherwise.
+// No node is assigned more than 25% above the average load.
+const DefaultLoadFactor = 1.25
+
+// ErrInvalidLoadFactor is returned by GetWithLoad when the ring was configured with a load factor below 1
+var ErrInvalidLoadFactor = errors.New("load factor must be at least 1")
+
+// WithBoundedLoad sets the load factor c used by GetWithLoad. Each node accepts at most ceil(c * average load)
+// in-flight keys; c must be at least 1, and values close to 1 trade more key movement for a tighter balance.
+func WithBoundedLoad(c float64) Option {
+	return func(cfg *config) {
+		cfg.loadFactor = c
+	}
+}
+
+// assignment tracks a key handed out by GetWithLoad until it is released with Done
+type assignment struct {
+	node  string
+	count int
+}
+
+// GetWithLoad retrieves a node for the key using consistent hashing with bounded loads (Mirrokni, Thorup and Zadimoghaddam).
+// The key goes to the closest node clockwise, like Get, unless that node is at capacity, in which case it spills over to the
+// next node in the ring that is not. The assignment counts against the node's load until Done is called for the key.
+// A key that is already in flight is returned the same node, unless that node has gone down since, in which case all of
+// the key's in-flight assignments move to a new node. Nodes that are down are skipped.
+func (r *Ring) GetWithLoad(key string) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.loadFactor < 1 || math.IsNaN(r.loadFactor) {
+		return "", ErrInvalidLoadFactor
+	}
+	v := r.current()
+	if v.available() == 0 {
+		return "", ErrNotEnoughNodes
+	}
+
+	a, ok := r.assignments[key]
+	if ok && v.states[a.node] != Down {
+		a.count++
+		r.loads[a.node]++
+		r.totalLoad++
+		return a.node, nil
+	}
+	if ok {
+		// the key's node went down while it was in flight, so its assignments are placed again
+		r.loads[a.node] -= a.count
+		if r.loads[a.node] == 0 {
+			delete(r.loads, a.node)
+		}
+		r.totalLoad -= a.count
+	} else {
+		a = &assignment{}
+		r.assignments[key] = a
+	}
+
+	capacity := r.capacity()
+	var chosen string
+	v.nodes.walk(v.locate(key), func(n *node) bool {
+		if v.states[n.ID] != Down && r.loads[n.ID] < capacity {
+			chosen = n.ID
+			return false
+		}
+		return true
+	})
+
+	a.node = chosen
+	a.count++
+	r.loads[chosen] += a.count
+	r.totalLoad += a.count
+	return chosen, nil
+}
+
+// Done releases one in-flight assignment of the key made by GetWithLoad
+func (r *Ring) Done(key string) {
+	r.Lock()
+	defer r.Unlock()
+
+	a, ok := r.assignments[key]
+	if !ok {
+		return
+	}
+	a.count--
+	if a.count == 0 {
+		delete(r.assignments, key)
+	}
+	r.loads[a.node]--
+	if r.loads[a.node] == 0 {
+		delete(r.loads, a.node)
+	}
+	r.totalLoad--
+}
+
+// Loads returns the number of in-flight keys assigned to each node by GetWithLoad
+func (r *Ring) Loads() map[string]int {
+	r.Lock()
+	defer r.Unlock()
+
+	loads := make(map[string]int, len(r.loads))
+	for id, load := range r.loads {
+		loads[id] = load
+	}
+	return loads
+}
+
+// capacity is the most in-flight keys a node that is not down may hold once one more key is assigned.
+// Callers must hold the lock.
+func (r *Ring) capacity() int {
+	return int(math.Ceil(r.loadFactor * float64(r.totalLoad+1) / float64(r.current().available())))
+}
+
+// forgetLoad drops the in-flight assignments of a node leaving the ring. Callers must hold the lock.
+func (r *Ring) forgetLoad(id string) {
+	for key, a := range r.assignments {
+		if a.node == id {
+			delete(r.assignments, key)
+		}
+	}
+	r.totalLoad -= r.loads[id]
+	delete(r.loads, id)
+}
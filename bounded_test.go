@@ -0,0 +1,92 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestGetWithLoadIsBounded(t *testing.T) {
+	ring := chring.NewRing(chring.WithBoundedLoad(1.25))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+
+	for i := 0; i < 100; i++ {
+		if _, err := ring.GetWithLoad(fmt.Sprintf("user_%d", i)); err != nil {
+			t.Fatalf("got error %v, want nil from GetWithLoad", err)
+		}
+	}
+	for id, load := range ring.Loads() {
+		if load > 32 {
+			t.Errorf("got load %d on %q, want at most ceil(1.25 * 100 / 4) = 32", load, id)
+		}
+	}
+
+	ring = chring.NewRing(chring.WithBoundedLoad(1))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	for i := 0; i < 100; i++ {
+		_, _ = ring.GetWithLoad(fmt.Sprintf("user_%d", i))
+	}
+	for _, n := range NodeList {
+		if got := ring.Loads()[n]; got != 25 {
+			t.Errorf("got load %d on %q, want 25 with a load factor of 1", got, n)
+		}
+	}
+}
+
+func TestGetWithLoadDone(t *testing.T) {
+	ring := newSeededRing()
+
+	first, _ := ring.GetWithLoad("user A")
+	second, _ := ring.GetWithLoad("user A")
+	if first != second {
+		t.Errorf("got %q then %q, want the same node for a key in flight", first, second)
+	}
+	if got := ring.Loads()[first]; got != 2 {
+		t.Errorf("got load %d, want 2", got)
+	}
+
+	ring.Done("user A")
+	ring.Done("user A")
+	ring.Done("user A") // releasing more than was assigned is a no-op
+	if got := len(ring.Loads()); got != 0 {
+		t.Errorf("got %d loaded nodes, want 0 after every key is done", got)
+	}
+
+	if _, err := chring.NewRing().GetWithLoad("user A"); err != chring.ErrNotEnoughNodes {
+		t.Errorf("got error %v, want %v on an empty ring", err, chring.ErrNotEnoughNodes)
+	}
+	if _, err := chring.NewRing(chring.WithBoundedLoad(0.5)).GetWithLoad("user A"); err != chring.ErrInvalidLoadFactor {
+		t.Errorf("got error %v, want %v for a load factor below 1", err, chring.ErrInvalidLoadFactor)
+	}
+}
+
+func TestGetWithLoadReroutesDownNodes(t *testing.T) {
+	ring := newSeededRing()
+
+	first, _ := ring.GetWithLoad("user A")
+	if err := ring.SetState(first, chring.Down); err != nil {
+		t.Fatal(err)
+	}
+	second, err := ring.GetWithLoad("user A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Fatalf("got down node %q for a key in flight, want it moved", second)
+	}
+	loads := ring.Loads()
+	if loads[first] != 0 || loads[second] != 2 {
+		t.Errorf("got loads %v, want both assignments on %q", loads, second)
+	}
+
+	ring.Done("user A")
+	ring.Done("user A")
+	if got := len(ring.Loads()); got != 0 {
+		t.Errorf("got %d loaded nodes, want 0 after every key is done", got)
+	}
+}
@@ -3,128 +3,337 @@ package chring
 import (
 	"errors"
 	"hash/crc32"
-	"sort"
+	"math"
 	"sync"
+	"sync/atomic"
 )
 
 // Ring is a consistent hash ring. Use New() to create a ring. You may change out the hasher function to change key balancing if needed.
+// Each node may be placed in the ring at several positions (virtual nodes); all of them map back to the same node ID.
+// Lookups read an immutable snapshot of the ring and never block; changes are serialized by the embedded mutex and
+// publish a new snapshot when done.
 type Ring struct {
 	sync.Mutex
-	Nodes  nodes
-	Hasher func(id string) uint32
+	state atomic.Pointer[RingView]
+	epoch uint64
+	// subscribers to change events, see Subscribe
+	subscribers map[int]chan RingEvent
+	nextSub     int
+	// Hasher places nodes and keys in the ring. Set it before adding nodes and do not change it while the ring is in use.
+	Hasher   func(id string) uint32
+	hasher   Hasher
+	replicas int
+	probes   int
+	members  map[string]*member
+	// stacked rings keep virtual nodes that collide at the same position instead of moving them, see place
+	stacked bool
+	// states of the nodes that are not active, replaced rather than modified as views share it
+	states map[string]NodeState
+
+	// bounded load bookkeeping, see GetWithLoad
+	loadFactor  float64
+	loads       map[string]int
+	assignments map[string]*assignment
+	totalLoad   int
+}
+
+// Option configures a ring when passed to NewRing or one of the other ring constructors
+type Option func(*config)
+
+// config holds the settings made by options. Each constructor reads the settings that apply to its ring and
+// documents which options it honors.
+type config struct {
+	hasher     Hasher // nil leaves the constructor's default hasher
+	replicas   int
+	probes     int
+	loadFactor float64
+}
+
+// newConfig applies the options over the default settings
+func newConfig(opts []Option) config {
+	c := config{replicas: 1, probes: 1, loadFactor: DefaultLoadFactor}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithReplicas sets how many virtual nodes Add places in the ring for each node. The default is 1.
+func WithReplicas(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.replicas = n
+		}
+	}
 }
 
 // New creates a new consistent hash ring with a default hashing algo
-func NewRing() *Ring {
-	return &Ring{Nodes: []*node{}, Hasher: DefaultHasher}
+func NewRing(opts ...Option) *Ring {
+	c := newConfig(opts)
+	r := &Ring{}
+	r.init()
+	if c.hasher != nil {
+		r.Hasher, r.hasher = c.hasher.Hash32, c.hasher
+	}
+	r.replicas, r.probes, r.loadFactor = c.replicas, c.probes, c.loadFactor
+	r.publish(nodes{})
+	return r
 }
 
-// Add inserts a new node into the hash ring
+// init sets the defaults of a new ring
+func (r *Ring) init() {
+	r.Hasher = DefaultHasher
+	r.replicas = 1
+	r.probes = 1
+	r.members = make(map[string]*member)
+	r.loadFactor = DefaultLoadFactor
+	r.loads = make(map[string]int)
+	r.assignments = make(map[string]*assignment)
+	r.subscribers = make(map[int]chan RingEvent)
+}
+
+// Add inserts a new node into the hash ring using the ring's configured replica count
 func (r *Ring) Add(id string) {
+	r.AddWithReplicas(id, r.replicas)
+}
+
+// AddWithReplicas inserts a new node into the hash ring at n virtual positions
+func (r *Ring) AddWithReplicas(id string, n int) {
+	r.Lock()
+	defer r.Unlock()
+
+	if n < 1 {
+		n = 1
+	}
+	r.addMember(&member{ID: id, Replicas: n, Weight: float64(n) / float64(r.replicas)})
+}
+
+// AddWeighted inserts a new node into the hash ring owning roughly weight times the hash space of a node added with Add.
+// The weight scales the ring's replica count, so use WithReplicas to get finer grained weighting.
+func (r *Ring) AddWeighted(id string, weight float64) error {
+	if !validWeight(weight) {
+		return ErrInvalidWeight
+	}
+
 	r.Lock()
 	defer r.Unlock()
 
+	r.addMember(&member{ID: id, Replicas: weightedReplicas(r.replicas, weight), Weight: weight})
+	return nil
+}
+
+// SetWeight changes the weight of a node already in the ring. The node keeps its existing virtual nodes where it can,
+// so only the hash space gained or lost by the node changes hands. Positions pinned with AddAt are not affected.
+func (r *Ring) SetWeight(id string, weight float64) error {
+	if !validWeight(weight) {
+		return ErrInvalidWeight
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	m, ok := r.members[id]
+	if !ok {
+		return ErrNotFound
+	}
+	m.Weight = weight
+	m.Replicas = weightedReplicas(r.replicas, weight)
+	old := r.publish(r.arrange(m, r.current().nodes.without(id)))
+	r.notify(WeightChanged, id, old)
+	return nil
+}
+
+// AddAt pins a node at the given hash positions so that operators can balance the ring by hand. If the node is
+// already in the ring, the positions are added to the ones it has. No position is placed if any of them is already
+// taken by a node, in which case ErrCollision is returned.
+func (r *Ring) AddAt(id string, hashIDs ...uint32) error {
+	if len(hashIDs) == 0 {
+		return ErrNoPositions
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	current := r.current().nodes
+	seen := make(map[uint32]bool, len(hashIDs))
+	pinned := make([]*node, len(hashIDs))
+	for i, hashID := range hashIDs {
+		if seen[hashID] || current.occupied(hashID) {
+			return ErrCollision
+		}
+		seen[hashID] = true
+		pinned[i] = &node{ID: id, HashID: hashID}
+	}
+
+	m, ok := r.members[id]
+	if !ok {
+		m = &member{ID: id, Weight: 1}
+		r.members[id] = m
+	}
+	for _, n := range pinned {
+		n.member = m
+	}
+	m.Pinned = append(m.Pinned, hashIDs...)
+	old := r.publish(current.with(pinned...))
+	r.notify(NodeAdded, id, old)
+	return nil
+}
+
+// Weight returns the weight of the given node
+func (r *Ring) Weight(id string) (float64, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	m, ok := r.members[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return m.Weight, nil
+}
+
+// addMember records the member and places its virtual nodes, unless a node with the same ID is already in the ring.
+// Callers must hold the lock.
+func (r *Ring) addMember(m *member) {
 	// don't insert the same node more than once
-	if r.findNode(id) == 0 && len(r.Nodes) > 0 && r.Nodes[0].ID == id {
-		// TODO: why is search returning 0 on miss? should be -1, yeah?
+	if _, ok := r.members[m.ID]; ok {
 		return
 	}
+	r.members[m.ID] = m
+	old := r.publish(r.arrange(m, r.current().nodes))
+	r.notify(NodeAdded, m.ID, old)
+}
+
+// memberNodes creates the member's pinned and hashed virtual nodes. Hashed virtual nodes are moved off positions
+// that are taken, see place.
+func (r *Ring) memberNodes(m *member, taken func(hashID uint32) bool) []*node {
+	ns := make([]*node, 0, m.Replicas+len(m.Pinned))
+	own := make(map[uint32]bool, m.Replicas+len(m.Pinned))
+	for _, hashID := range m.Pinned {
+		own[hashID] = true
+		ns = append(ns, &node{ID: m.ID, HashID: hashID, member: m})
+	}
+	m.moved = false
+	for i := 0; i < m.Replicas; i++ {
+		hashID, moved := r.place(m.ID, i, func(hashID uint32) bool { return own[hashID] || taken(hashID) })
+		m.moved = m.moved || moved
+		own[hashID] = true
+		ns = append(ns, &node{ID: m.ID, HashID: hashID, member: m})
+	}
+	return ns
+}
+
+// weightedReplicas converts a weight into a virtual node count, never dropping a node out of the ring entirely
+func weightedReplicas(replicas int, weight float64) int {
+	n := int(math.Round(weight * float64(replicas)))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
 
-	n := newNode(id, r.Hasher)
-	r.Nodes = append(r.Nodes, n)
-	sort.Sort(r.Nodes)
+// validWeight reports whether weight is usable as a node weight
+func validWeight(weight float64) bool {
+	return weight > 0 && !math.IsInf(weight, 0) && !math.IsNaN(weight)
 }
 
 // Get retrievs the closest node in the hash ring for the given key
 func (r *Ring) Get(key string) string {
-	r.Lock()
-	r.Unlock()
+	return r.current().Get(key)
+}
 
-	if len(r.Nodes) == 0 {
-		return "" // should error?
-	}
+// Nodes returns the ID of every node in the ring once, in ring order
+func (r *Ring) Nodes() []string {
+	return r.current().Nodes()
+}
 
-	i := r.search(key)
-	if i >= r.Nodes.Len() || i == -1 {
-		i = 0 // default to initial node
-	}
-	return r.Nodes[i].ID
+// GetN retrieves the n distinct nodes that follow the given key clockwise in the hash ring, closest first.
+// This is useful for replicating a key to several nodes. Virtual nodes of a node already chosen are skipped.
+func (r *Ring) GetN(key string, n int) ([]string, error) {
+	return r.current().GetN(key, n)
 }
 
 var ErrNotFound = errors.New("node not found")
 
+// ErrNotEnoughNodes is returned when more distinct nodes are requested than the ring holds
+var ErrNotEnoughNodes = errors.New("not enough nodes in the ring")
+
+// ErrNegativeCount is returned when a negative number of nodes is requested
+var ErrNegativeCount = errors.New("node count must not be negative")
+
+// ErrCollision is returned when a node is pinned at a hash position that is already taken
+var ErrCollision = errors.New("hash position already taken")
+
+// ErrNoPositions is returned when a node is pinned without any hash positions
+var ErrNoPositions = errors.New("no hash positions given")
+
+// ErrInvalidWeight is returned when a node weight is not a positive, finite number
+var ErrInvalidWeight = errors.New("weight must be a positive number")
+
+// Remove takes the node and all of its virtual nodes out of the hash ring
 func (r *Ring) Remove(id string) error {
 	r.Lock()
 	defer r.Unlock()
 
-	i := r.findNode(id)
-	if i >= r.Nodes.Len() || i == -1 || r.Nodes[i].ID != id {
+	if _, ok := r.members[id]; !ok {
 		return ErrNotFound
 	}
-
-	r.Nodes = append(r.Nodes[:i], r.Nodes[i+1:]...)
+	delete(r.members, id)
+	if _, ok := r.states[id]; ok {
+		r.setState(id, Active)
+	}
+	ns := r.current().nodes.without(id)
+	if r.collided() {
+		ns = r.layout() // the node may have pushed others off their positions
+	}
+	old := r.publish(ns)
+	r.forgetLoad(id)
+	r.notify(NodeRemoved, id, old)
 	return nil
 }
 
-// findNode is different than `search` in that it searches for an exact match for a node ID
+// findNode is different than the view's `search` in that it searches for an exact match for a node ID.
+// It returns the index of the node's primary position, or -1 if the node is not in the ring.
 func (r *Ring) findNode(id string) int {
-	nextNode := sort.Search(len(r.Nodes), func(i int) bool {
-		return r.Nodes[i].HashID > r.Hasher(id)
-	})
-	if nextNode == 0 {
-		return nextNode
-	}
-	return nextNode - 1
+	return r.current().nodes.find(id, r.Hasher(id))
+}
+
+// findNodes returns the indexes of every virtual node belonging to the given node ID, in ring order
+func (r *Ring) findNodes(id string) []int {
+	return r.current().nodes.indexesOf(id)
 }
 
-// search is different than `findNode` in that it searches for any node next in the hash ring for a given key
-func (r *Ring) search(key string) int {
-	return sort.Search(len(r.Nodes), func(i int) bool {
-		return r.Nodes[i].HashID > r.Hasher(key)
-	})
+// nodeIDs returns each distinct node ID once, in the order they first appear in the ring
+func (r *Ring) nodeIDs() []string {
+	return r.current().nodes.ids()
 }
 
 // searchByHashID finds the node closest to the given hashID
 func (r *Ring) searchByHashID(hashID uint32) int {
-	nodeAfter := sort.Search(len(r.Nodes), func(i int) bool {
-		return r.Nodes[i].HashID > hashID
-	})
-	if nodeAfter <= 0 {
-		return nodeAfter
-	}
-	return nodeAfter - 1
+	return r.current().nodes.searchByHashID(hashID)
 }
 
 // node comprises nodes, which are placed in the consistent hash ring
-type node struct {
-	ID     string
-	HashID uint32
-}
+type node = point[uint32]
 
-// newNode creates a new node to go into the hash ring
-func newNode(id string, fn hasher) *node {
-	return &node{
-		ID:     id,
-		HashID: fn(id),
-	}
+// nodes is an alias type for easy reference for matching the swap interface
+type nodes = points[uint32]
+
+// member tracks a physical node in the ring, independent of how many virtual nodes represent it
+type member struct {
+	ID       string
+	Replicas int
+	Weight   float64
+	Pinned   []uint32
+	// Addr and Tags are set by AddNode and never change afterwards, so lookups may read them without locking
+	Addr string
+	Tags map[string]string
+	// value is the node a TypedRing was given, set once when the member is added
+	value any
+	// moved is set when a virtual node of the member was moved off a taken position
+	moved bool
 }
 
 // DefaultHasher uses crc32
 func DefaultHasher(id string) uint32 {
 	return crc32.ChecksumIEEE([]byte(id))
 }
-
-// hasher is an alias type used in the Ring type
-type hasher func(id string) uint32
-
-// nodes is an alias type for easy reference for matching the swap interface
-type nodes []*node
-
-// Len() is for matching the swap interface
-func (n nodes) Len() int { return len(n) }
-
-// Swap() is for matching the swap interface
-func (n nodes) Swap(i, j int) { n[i], n[j] = n[j], n[i] }
-
-// Less() is for matching the swap interface
-func (n nodes) Less(i, j int) bool { return n[i].HashID < n[j].HashID }
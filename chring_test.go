@@ -1,6 +1,8 @@
 package chring_test
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/sethgrid/chring"
@@ -22,8 +24,8 @@ func TestAddIsConsistent(t *testing.T) {
 	ring.Add("a")
 	ring.Add("a")
 
-	if ring.Nodes.Len() != 1 {
-		t.Errorf("want 1 node, got %d", ring.Nodes.Len())
+	if len(ring.Nodes()) != 1 {
+		t.Errorf("want 1 node, got %d", len(ring.Nodes()))
 	}
 }
 
@@ -77,6 +79,249 @@ func TestConsistentHashRing(t *testing.T) {
 	}
 }
 
+func TestVirtualNodes(t *testing.T) {
+	ring := chring.NewRing(chring.WithReplicas(160))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	if got, want := chring.VirtualNodes(ring), 160*len(NodeList); got != want {
+		t.Fatalf("got %d virtual nodes, want %d", got, want)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[ring.Get(fmt.Sprintf("user_%d", i))]++
+	}
+	for _, n := range NodeList {
+		if counts[n] < 1500 {
+			t.Errorf("got %d of 10000 keys on %q, want a more even distribution", counts[n], n)
+		}
+	}
+
+	if err := ring.Remove("node 1"); err != nil {
+		t.Fatalf("got error %v, want nil when removing a known node", err)
+	}
+	if got, want := chring.VirtualNodes(ring), 160*(len(NodeList)-1); got != want {
+		t.Errorf("got %d virtual nodes after remove, want %d", got, want)
+	}
+	for i := 0; i < 1000; i++ {
+		if got := ring.Get(fmt.Sprintf("user_%d", i)); got == "node 1" {
+			t.Fatalf("got %q for a key after it was removed", got)
+		}
+	}
+}
+
+func TestAddWithReplicas(t *testing.T) {
+	ring := chring.NewRing()
+	ring.AddWithReplicas("a", 10)
+	ring.AddWithReplicas("a", 10)
+	ring.Add("b")
+
+	if got, want := chring.VirtualNodes(ring), 11; got != want {
+		t.Errorf("got %d virtual nodes, want %d", got, want)
+	}
+}
+
+func TestWeightedNodes(t *testing.T) {
+	ring := chring.NewRing(chring.WithReplicas(100))
+	if err := ring.AddWeighted("big", 4); err != nil {
+		t.Fatalf("got error %v, want nil adding a weighted node", err)
+	}
+	if err := ring.AddWeighted("small", 1); err != nil {
+		t.Fatalf("got error %v, want nil adding a weighted node", err)
+	}
+	if got, want := chring.VirtualNodes(ring), 500; got != want {
+		t.Errorf("got %d virtual nodes, want %d", got, want)
+	}
+
+	share := func() float64 {
+		big := 0
+		for i := 0; i < 10000; i++ {
+			if ring.Get(fmt.Sprintf("user_%d", i)) == "big" {
+				big++
+			}
+		}
+		return float64(big) / 10000
+	}
+	if got := share(); got < 0.7 || got > 0.9 {
+		t.Errorf("got %.2f of keys on the big node, want about 0.8", got)
+	}
+
+	if err := ring.SetWeight("big", 1); err != nil {
+		t.Fatalf("got error %v, want nil setting a weight", err)
+	}
+	if got := share(); got < 0.4 || got > 0.6 {
+		t.Errorf("got %.2f of keys on the big node after reweighting, want about 0.5", got)
+	}
+	if got, _ := ring.Weight("big"); got != 1 {
+		t.Errorf("got weight %v, want 1", got)
+	}
+
+	if err := ring.SetWeight("node x", 2); err != chring.ErrNotFound {
+		t.Errorf("got error %v, want %v when reweighting an unknown node", err, chring.ErrNotFound)
+	}
+	if err := ring.AddWeighted("zero", 0); err != chring.ErrInvalidWeight {
+		t.Errorf("got error %v, want %v for a zero weight", err, chring.ErrInvalidWeight)
+	}
+
+	if err := ring.Remove("big"); err != nil {
+		t.Fatalf("got error %v, want nil removing a weighted node", err)
+	}
+	if got := ring.Get("user_1"); got != "small" {
+		t.Errorf("got %q, want %q as the only remaining node", got, "small")
+	}
+}
+
+func TestAddAt(t *testing.T) {
+	ring := chring.NewRing()
+	if err := ring.AddAt("a", 1000, 3000000000); err != nil {
+		t.Fatalf("got error %v, want nil pinning a node", err)
+	}
+	if err := ring.AddAt("b", 2000000000); err != nil {
+		t.Fatalf("got error %v, want nil pinning a node", err)
+	}
+	if err := ring.AddAt("c", 2000000000); err != chring.ErrCollision {
+		t.Errorf("got error %v, want %v when pinning on a taken position", err, chring.ErrCollision)
+	}
+	if err := ring.AddAt("c", 5, 5); err != chring.ErrCollision {
+		t.Errorf("got error %v, want %v when pinning twice on the same position", err, chring.ErrCollision)
+	}
+	if err := ring.AddAt("c"); err != chring.ErrNoPositions {
+		t.Errorf("got error %v, want %v when pinning without positions", err, chring.ErrNoPositions)
+	}
+	if got, want := chring.VirtualNodes(ring), 3; got != want {
+		t.Errorf("got %d virtual nodes, want %d", got, want)
+	}
+
+	// known hashIDs given default hasher: Raz -> 1548738824, Foo -> 3023971265
+	if got, want := ring.Get("Raz"), "b"; got != want {
+		t.Errorf("got %q, want %q for Get(%q)", got, want, "Raz")
+	}
+	if got, want := ring.Get("Foo"), "a"; got != want {
+		t.Errorf("got %q, want %q for Get(%q)", got, want, "Foo")
+	}
+
+	if err := ring.Remove("a"); err != nil {
+		t.Fatalf("got error %v, want nil removing a pinned node", err)
+	}
+	if got, want := ring.Get("Foo"), "b"; got != want {
+		t.Errorf("got %q, want %q for Get(%q) after removing the pinned node", got, want, "Foo")
+	}
+}
+
+func TestGetN(t *testing.T) {
+	ring := chring.NewRing(chring.WithReplicas(50))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+
+	for _, key := range []string{"user A", "user B", "user C", "user D"} {
+		got, err := ring.GetN(key, 3)
+		if err != nil {
+			t.Fatalf("got error %v, want nil for GetN(%q, 3)", err, key)
+		}
+		if len(got) != 3 {
+			t.Fatalf("got %d nodes, want 3 for GetN(%q, 3)", len(got), key)
+		}
+		if got[0] != ring.Get(key) {
+			t.Errorf("got %q as the first node, want %q to match Get(%q)", got[0], ring.Get(key), key)
+		}
+		seen := make(map[string]bool)
+		for _, n := range got {
+			if seen[n] {
+				t.Errorf("got %q more than once in %v", n, got)
+			}
+			seen[n] = true
+		}
+	}
+
+	all, err := ring.GetN("user A", len(NodeList))
+	if err != nil || len(all) != len(NodeList) {
+		t.Errorf("got %v (error %v), want every node", all, err)
+	}
+	if _, err := ring.GetN("user A", len(NodeList)+1); err != chring.ErrNotEnoughNodes {
+		t.Errorf("got error %v, want %v when asking for more nodes than exist", err, chring.ErrNotEnoughNodes)
+	}
+	if _, err := ring.GetN("user A", -1); err != chring.ErrNegativeCount {
+		t.Errorf("got error %v, want %v for a negative count", err, chring.ErrNegativeCount)
+	}
+}
+
+func TestConcurrentReadsAndWrites(t *testing.T) {
+	ring := chring.NewRing(chring.WithReplicas(20))
+	ring.Add("stable")
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if got := ring.Get(fmt.Sprintf("user_%d", i)); got == "" {
+					t.Errorf("got no node for a key while the ring always holds one")
+					return
+				}
+				_, _ = ring.GetN(fmt.Sprintf("user_%d", i), 1)
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("node %d", i%10)
+		ring.Add(id)
+		_ = ring.SetWeight(id, 2)
+		_ = ring.Remove(id)
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestConcurrentRingManager64(t *testing.T) {
+	rm := chring.NewRingManager64(chring.WithReplicas(20))
+	if err := rm.AddNode("stable"); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if !In("stable", rm.GetNodes()) {
+					t.Errorf("got nodes without the node that is always in the ring")
+					return
+				}
+				if _, err := rm.GetKeys("stable"); err != nil {
+					t.Errorf("got error %v getting keys of a node in the ring", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("node %d", i%10)
+		_ = rm.AddNode(id)
+		_ = rm.AddKey(fmt.Sprintf("user_%d", i))
+		_ = rm.RemoveNode(id)
+	}
+	close(done)
+	wg.Wait()
+}
+
 func In(s string, slice []string) bool {
 	for _, e := range slice {
 		if e == s {
@@ -0,0 +1,75 @@
+package chring
+
+import (
+	"sort"
+	"strconv"
+)
+
+// maxRelocations is how many salted positions place tries before giving up on finding a free one
+const maxRelocations = 32
+
+// place finds the position of the i-th hashed virtual node of the given ID. Two IDs may hash to the same position,
+// and a node sharing a position with another owns none of the keys there. So a virtual node whose position is taken
+// is moved: it is hashed again with an increasing salt until it lands on a free position. Positions only depend on
+// the nodes in the ring, as layout places nodes in ID order: of two colliding nodes, the one with the greater ID moves.
+func (r *Ring) place(id string, i int, taken func(hashID uint32) bool) (hashID uint32, moved bool) {
+	key := id
+	if i > 0 {
+		key = id + "#" + strconv.Itoa(i)
+	}
+	hashID = r.Hasher(key)
+	if r.stacked {
+		return hashID, false
+	}
+	for salt := 1; taken(hashID) && salt <= maxRelocations; salt++ {
+		hashID = r.Hasher(key + "\x00" + strconv.Itoa(salt))
+		moved = true
+	}
+	return hashID, moved
+}
+
+// arrange places the member's virtual nodes into base, which must not hold any of them. Nodes are only added
+// incrementally while no virtual node had to move; otherwise the whole ring is laid out again so that the result
+// does not depend on the order nodes were added in. Callers must hold the lock.
+func (r *Ring) arrange(m *member, base nodes) nodes {
+	ns := r.memberNodes(m, base.occupied)
+	if r.collided() {
+		return r.layout()
+	}
+	return base.with(ns...)
+}
+
+// collided reports whether any member has a virtual node that was moved. Callers must hold the lock.
+func (r *Ring) collided() bool {
+	for _, m := range r.members {
+		if m.moved {
+			return true
+		}
+	}
+	return false
+}
+
+// layout places every member from scratch: pinned positions first, as AddAt never lets them collide, then the
+// hashed virtual nodes of each member in ID order. Callers must hold the lock.
+func (r *Ring) layout() nodes {
+	ids := make([]string, 0, len(r.members))
+	taken := make(map[uint32]bool)
+	for id, m := range r.members {
+		ids = append(ids, id)
+		for _, hashID := range m.Pinned {
+			taken[hashID] = true
+		}
+	}
+	sort.Strings(ids)
+
+	var ns nodes
+	for _, id := range ids {
+		placed := r.memberNodes(r.members[id], func(hashID uint32) bool { return taken[hashID] })
+		for _, n := range placed {
+			taken[n.HashID] = true
+		}
+		ns = append(ns, placed...)
+	}
+	sort.Sort(ns)
+	return ns
+}
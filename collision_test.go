@@ -0,0 +1,77 @@
+package chring_test
+
+import (
+	"hash/crc32"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+// these IDs have the same CRC32
+const (
+	collidingLow  = "host-4640a9050e"
+	collidingHigh = "host-e1a31e0316"
+)
+
+func TestCollidingNodes(t *testing.T) {
+	hashID := crc32.ChecksumIEEE([]byte(collidingLow))
+	if crc32.ChecksumIEEE([]byte(collidingHigh)) != hashID {
+		t.Fatal("test IDs do not collide")
+	}
+
+	forward := chring.NewRing()
+	forward.Add("node 1")
+	forward.Add(collidingLow)
+	forward.Add(collidingHigh)
+	backward := chring.NewRing()
+	backward.Add(collidingHigh)
+	backward.Add(collidingLow)
+	backward.Add("node 1")
+
+	if forward.Snapshot().Checksum() != backward.Snapshot().Checksum() {
+		t.Error("got different layouts depending on the order colliding nodes were added in")
+	}
+	for _, ring := range []*chring.Ring{forward, backward} {
+		if got := len(ring.Nodes()); got != 3 {
+			t.Errorf("got %d nodes, want 3", got)
+		}
+		if got := ring.OwnerOf(hashID - 1); got != collidingLow {
+			t.Errorf("got %q at the shared position, want the lower ID %q to keep it", got, collidingLow)
+		}
+		if len(ring.Ranges(collidingHigh)) == 0 {
+			t.Errorf("got no ranges for %q, want it moved to a free position", collidingHigh)
+		}
+	}
+
+	// Remove takes out exactly the given node, and the other one goes back to its own position
+	if err := forward.Remove(collidingLow); err != nil {
+		t.Fatal(err)
+	}
+	if got := forward.Nodes(); len(got) != 2 || !In(collidingHigh, got) {
+		t.Errorf("got nodes %v after removing %q", got, collidingLow)
+	}
+	alone := chring.NewRing()
+	alone.Add("node 1")
+	alone.Add(collidingHigh)
+	if forward.Snapshot().Checksum() != alone.Snapshot().Checksum() {
+		t.Errorf("got %q left at its moved position", collidingHigh)
+	}
+}
+
+func TestCollidingKeysStayInPlace(t *testing.T) {
+	ringManager := chring.NewRingManager()
+	_ = ringManager.AddNode("node a")
+	_ = ringManager.AddNode("node b")
+	_ = ringManager.AddKey(collidingLow)
+	_ = ringManager.AddKey(collidingHigh)
+
+	keysInA, _ := ringManager.GetKeys("node a")
+	keysInB, _ := ringManager.GetKeys("node b")
+	keys := append(keysInA, keysInB...)
+	if len(keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(keys))
+	}
+	if len(keysInA) != 0 && len(keysInB) != 0 {
+		t.Error("got colliding keys on different nodes, want them both where their hash places them")
+	}
+}
@@ -0,0 +1,62 @@
+package chring
+
+import (
+	"math"
+	"sort"
+)
+
+// Migration is a range of the hash space whose owner changed between two views of a ring. Keys hashing into Range
+// need to be copied from the From node to the To node.
+type Migration struct {
+	Range    HashRange
+	From, To string
+}
+
+// Diff reports every range of the hash space whose owner differs between the old and new views, such as the views
+// taken before and after an Add or Remove, so that data movers know exactly what to copy. Ranges never wrap around
+// the end of the hash space, adjacent ranges with the same owners are merged, and ranges without an owner in either
+// view are left out. Ownership follows Get without probes: a key belongs to the first virtual node past its hash
+// whose node is not down, so setting a node down or back up reports the ranges that move.
+func (r *Ring) Diff(old, new *RingView) []Migration {
+	return diff(old, new)
+}
+
+// diff implements Ring.Diff. Every position of a virtual node in either view starts a new segment of the hash space
+// whose owner is the same for all of its positions, so only the segments need comparing.
+func diff(old, new *RingView) []Migration {
+	bounds := []uint32{0}
+	for _, v := range []*RingView{old, new} {
+		if v == nil {
+			continue
+		}
+		for _, n := range v.nodes {
+			bounds = append(bounds, n.HashID)
+		}
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	var migrations []Migration
+	for i, start := range bounds {
+		if i > 0 && start == bounds[i-1] {
+			continue
+		}
+		end := uint32(math.MaxUint32)
+		for j := i + 1; j < len(bounds); j++ {
+			if bounds[j] != start {
+				end = bounds[j] - 1
+				break
+			}
+		}
+
+		from, to := old.OwnerOf(start), new.OwnerOf(start)
+		if from == to || from == "" || to == "" {
+			continue
+		}
+		if last := len(migrations) - 1; last >= 0 && migrations[last].From == from && migrations[last].To == to && migrations[last].Range.End == start-1 {
+			migrations[last].Range.End = end
+			continue
+		}
+		migrations = append(migrations, Migration{Range: HashRange{Start: start, End: end}, From: from, To: to})
+	}
+	return migrations
+}
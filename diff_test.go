@@ -0,0 +1,82 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestDiff(t *testing.T) {
+	ring := chring.NewRing(chring.WithReplicas(20))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+
+	checkDiff := func(old, new *chring.RingView) []chring.Migration {
+		migrations := ring.Diff(old, new)
+		for i := 0; i < 5000; i++ {
+			key := fmt.Sprintf("user_%d", i)
+			hashID := chring.DefaultHasher(key)
+			from, to := old.Get(key), new.Get(key)
+
+			var found *chring.Migration
+			for j := range migrations {
+				if migrations[j].Range.Contains(hashID) {
+					found = &migrations[j]
+				}
+			}
+			switch {
+			case from == to && found != nil:
+				t.Errorf("got migration %+v for %q, want none as it stays on %q", *found, key, from)
+			case from != to && found == nil:
+				t.Errorf("got no migration for %q, want one from %q to %q", key, from, to)
+			case from != to && (found.From != from || found.To != to):
+				t.Errorf("got migration %+v for %q, want one from %q to %q", *found, key, from, to)
+			}
+		}
+		return migrations
+	}
+
+	before := ring.Snapshot()
+	ring.Add("node 5")
+	added := ring.Snapshot()
+	migrations := checkDiff(before, added)
+	if len(migrations) == 0 {
+		t.Fatal("got no migrations after adding a node")
+	}
+	for _, m := range migrations {
+		if m.To != "node 5" {
+			t.Errorf("got migration %+v, want every range to move to the new node", m)
+		}
+	}
+
+	_ = ring.Remove("node 2")
+	for _, m := range checkDiff(added, ring.Snapshot()) {
+		if m.From != "node 2" {
+			t.Errorf("got migration %+v, want every range to move off the removed node", m)
+		}
+	}
+
+	if got := ring.Diff(ring.Snapshot(), ring.Snapshot()); len(got) != 0 {
+		t.Errorf("got %d migrations between identical views, want 0", len(got))
+	}
+}
+
+func TestDiffWrapsAround(t *testing.T) {
+	ring := chring.NewRing()
+	_ = ring.AddAt("a", 100)
+	_ = ring.AddAt("b", 200)
+	before := ring.Snapshot()
+	_ = ring.Remove("a")
+
+	// a owned [200, max] and [0, 99], which now belong to b
+	want := []chring.Migration{
+		{Range: chring.HashRange{Start: 0, End: 99}, From: "a", To: "b"},
+		{Range: chring.HashRange{Start: 200, End: 4294967295}, From: "a", To: "b"},
+	}
+	got := ring.Diff(before, ring.Snapshot())
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -22,6 +22,25 @@ import (
 )
 
 func (r *Ring) drawChart(w http.ResponseWriter, req *http.Request) {
+	drawChart(w, req, r.current().nodes, r.Hasher, false)
+}
+
+func (r *Ring64) drawChart(w http.ResponseWriter, req *http.Request) {
+	drawChart(w, req, r.current(), r.Hasher, false)
+}
+
+// drawChart charts the manager's node ring, whose nodes own the keys after their positions
+func (rm *RingManager) drawChart(w http.ResponseWriter, req *http.Request) {
+	drawChart(w, req, rm.nodeRing.current().nodes, rm.nodeRing.Hasher, true)
+}
+
+func (rm *RingManager64) drawChart(w http.ResponseWriter, req *http.Request) {
+	drawChart(w, req, rm.nodeRing.current(), rm.nodeRing.Hasher, true)
+}
+
+// drawChart renders a ring of any hash width along with the keys and hash ids given in the request. keysAfter
+// selects how the legend computes each node's share of the hash space, see ownership.
+func drawChart[H position](w http.ResponseWriter, req *http.Request, ringNodes points[H], hasher func(id string) H, keysAfter bool) {
 	m, err := url.ParseQuery(req.URL.RawQuery)
 	if err != nil {
 		log.Println(err)
@@ -51,7 +70,7 @@ func (r *Ring) drawChart(w http.ResponseWriter, req *http.Request) {
 			square := 4
 			props := simpledraw.DefaultBasicProperties
 			props.Color = simpledraw.Pallate[(i+3)%len(simpledraw.Pallate)]
-			gc.DrawOnEdge(ring, hashAngle(r.Hasher(param)), square, 4, props)
+			gc.DrawOnEdge(ring, hashAngle(hasher(param)), square, 4, props)
 			legend.AppendElement(square, param, props)
 		}
 	}
@@ -60,24 +79,36 @@ func (r *Ring) drawChart(w http.ResponseWriter, req *http.Request) {
 		square := 4
 		props := simpledraw.DefaultBasicProperties
 		props.Color = simpledraw.Pallate[(i+3)%len(simpledraw.Pallate)]
-		gc.DrawOnEdge(ring, hashAngle(r.Hasher(param)), square, 4, props)
+		gc.DrawOnEdge(ring, hashAngle(hasher(param)), square, 4, props)
 		legend.AppendElement(square, param, props)
 	}
 
-	for i, n := range r.Nodes {
+	// virtual nodes share their physical node's color and legend entry
+	ids := ringNodes.ids()
+	colors := make(map[string]int, len(ids))
+	for i, id := range ids {
+		colors[id] = i
+	}
+	for _, n := range ringNodes {
 		circle := 0
 		props := simpledraw.DefaultBasicProperties
-		props.Color = simpledraw.Pallate[i%len(simpledraw.Pallate)]
+		props.Color = simpledraw.Pallate[colors[n.ID]%len(simpledraw.Pallate)]
 		gc.DrawOnEdge(ring, hashAngle(n.HashID), circle, 12, props)
-		legend.PrependElement(circle, n.ID, props)
+	}
+	shares := ownership(ringNodes, keysAfter)
+	for i, id := range ids {
+		circle := 0
+		props := simpledraw.DefaultBasicProperties
+		props.Color = simpledraw.Pallate[i%len(simpledraw.Pallate)]
+		legend.PrependElement(circle, fmt.Sprintf("%s (%.1f%%)", id, 100*shares[id]), props)
 	}
 
 	for i, param := range m["hashid[]"] {
 		triangle := 3
-		hashID, _ := strconv.Atoi(param)
+		hashID, _ := strconv.ParseUint(param, 10, 64)
 		props := simpledraw.DefaultBasicProperties
 		props.Color = simpledraw.Pallate[(i+5)%len(simpledraw.Pallate)]
-		gc.DrawOnEdge(ring, hashAngle(uint32(hashID)), triangle, 10, props)
+		gc.DrawOnEdge(ring, hashAngle(H(hashID)), triangle, 10, props)
 		hashStr := fmt.Sprintf("hash #%d", hashID)
 		legend.AppendElement(triangle, hashStr, props)
 	}
@@ -114,31 +145,50 @@ func ServeRing(r *Ring, addr string) {
 	log.Fatal(http.ListenAndServe(addr, nil))
 }
 
+// ServeRing64 presents a web view into your 64 bit consistent hash ring
+func ServeRing64(r *Ring64, addr string) {
+	http.HandleFunc("/ring.png", r.drawChart)
+	http.HandleFunc("/", htmlHandler("ring.html"))
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
 // ServeRingManager presents a web view into your consistent hash ring manager
 func ServeRingManager(rm *RingManager, addr string) {
-	http.HandleFunc("/ring.png", addKeysToCtx(rm, rm.nodeRing.drawChart))
+	keys := func() []string { return keyNames(rm.GetNodes(), rm.dataRing.current().nodes) }
+	http.HandleFunc("/ring.png", addKeysToCtx(keys, rm.drawChart))
+	http.HandleFunc("/", htmlHandler("ringmanager.html"))
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
+// ServeRingManager64 presents a web view into your 64 bit consistent hash ring manager
+func ServeRingManager64(rm *RingManager64, addr string) {
+	keys := func() []string { return keyNames(rm.GetNodes(), rm.dataRing.current()) }
+	http.HandleFunc("/ring.png", addKeysToCtx(keys, rm.drawChart))
 	http.HandleFunc("/", htmlHandler("ringmanager.html"))
 	log.Fatal(http.ListenAndServe(addr, nil))
 }
 
 // addKeysToCtx
-func addKeysToCtx(rm *RingManager, next http.HandlerFunc) http.HandlerFunc {
+func addKeysToCtx(keyList func() []string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		nodeList := rm.GetNodes()
-		ctx := r.Context()
-		var keys []string
-		for _, n := range rm.dataRing.Nodes {
-			if inList(n.ID, nodeList) {
-				continue
-			}
-			keys = append(keys, n.ID)
-		}
-		ctx = context.WithValue(ctx, "keys", keys)
+		ctx := context.WithValue(r.Context(), "keys", keyList())
 		req := r.WithContext(ctx)
 		next.ServeHTTP(w, req)
 	}
 }
 
+// keyNames lists the keys in a ring manager's data ring, which also holds the names of its nodes
+func keyNames[H position](nodeList []string, dataNodes points[H]) []string {
+	var keys []string
+	for _, n := range dataNodes {
+		if inList(n.ID, nodeList) {
+			continue
+		}
+		keys = append(keys, n.ID)
+	}
+	return keys
+}
+
 // inList is a simple helper to determine if a string slice contains a given string
 func inList(s string, list []string) bool {
 	for _, e := range list {
@@ -149,9 +199,9 @@ func inList(s string, list []string) bool {
 	return false
 }
 
-// hashAngle is a helper to find the angle in radians of the hashID in uint32 space
-func hashAngle(hashID uint32) float64 {
-	return 2 * math.Pi * float64(hashID) / float64(math.MaxUint32)
+// hashAngle is a helper to find the angle in radians of the hashID in the ring's uint32 or uint64 space
+func hashAngle[H position](hashID H) float64 {
+	return 2 * math.Pi * float64(hashID) / float64(^H(0))
 }
 
 // FindInGOPATH searches through all GOPATHS and attempts to find the given file
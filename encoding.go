@@ -0,0 +1,595 @@
+package chring
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// EncodingVersion is the version of the JSON and binary formats written by the Marshal methods of Ring and RingManager
+const EncodingVersion = 1
+
+// ErrUnsupportedVersion is returned when loading a ring encoded in a format version this package does not know
+var ErrUnsupportedVersion = errors.New("unsupported ring encoding version")
+
+// ErrHasherMismatch is returned when loading a ring that was encoded with a different hasher than the one it is loaded into
+var ErrHasherMismatch = errors.New("ring was encoded with a different hasher")
+
+// ErrUnnamedHasher is returned when encoding or loading a ring whose Hasher func was replaced directly rather than
+// set with WithHasher. Such a hasher cannot be named, so a loaded ring could not be checked to route keys alike.
+var ErrUnnamedHasher = errors.New("ring hasher was not set with WithHasher")
+
+// ErrCorrupt is returned when loading a ring from data that is damaged or inconsistent
+var ErrCorrupt = errors.New("ring encoding is corrupt")
+
+// magic numbers starting the binary formats
+var (
+	ringMagic    = []byte("chr")
+	managerMagic = []byte("chm")
+)
+
+// ringData is the layout of a ring as it is encoded. Positions are stored rather than recomputed, so a loaded ring
+// routes keys exactly like the ring it was encoded from.
+type ringData struct {
+	Version  int          `json:"version"`
+	Hasher   string       `json:"hasher"`
+	Replicas int          `json:"replicas"`
+	Probes   int          `json:"probes"`
+	Epoch    uint64       `json:"epoch"`
+	Nodes    []memberData `json:"nodes"`
+}
+
+// memberData is a node of an encoded ring
+type memberData struct {
+	ID        string            `json:"id"`
+	Weight    float64           `json:"weight"`
+	Positions []uint32          `json:"positions"` // hashed virtual nodes
+	Pinned    []uint32          `json:"pinned,omitempty"`
+	Addr      string            `json:"addr,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	State     NodeState         `json:"state,omitempty"`
+}
+
+// restored is a decoded ring that has been checked and is ready to replace the state of a ring
+type restored struct {
+	replicas, probes int
+	epoch            uint64
+	members          map[string]*member
+	states           map[string]NodeState
+	nodes            nodes
+}
+
+// MarshalJSON encodes the ring's layout: each node with its positions, weight, address, tags and state, along with
+// the name of the ring's hasher, its replica count, probe count and epoch. Values held by a TypedRing are not encoded.
+func (r *Ring) MarshalJSON() ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+	d, err := r.export()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(d)
+}
+
+// UnmarshalJSON replaces the ring's nodes with the ones encoded by MarshalJSON. The ring must use the hasher the
+// data was encoded with, or ErrHasherMismatch is returned and the ring is left unchanged. In-flight keys of
+// GetWithLoad are forgotten. The ring takes the encoded epoch, unless it is already past it, and subscribers are
+// sent a RingLoaded event.
+func (r *Ring) UnmarshalJSON(b []byte) error {
+	var d ringData
+	if err := json.Unmarshal(b, &d); err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if r.members == nil {
+		r.init()
+	}
+	loaded, err := r.prepare(d)
+	if err != nil {
+		return err
+	}
+	r.restore(loaded)
+	return nil
+}
+
+// MarshalBinary encodes the same layout as MarshalJSON in a compact binary format ending with a CRC32 checksum
+func (r *Ring) MarshalBinary() ([]byte, error) {
+	r.Lock()
+	defer r.Unlock()
+	d, err := r.export()
+	if err != nil {
+		return nil, err
+	}
+	return d.appendBinary(nil), nil
+}
+
+// UnmarshalBinary replaces the ring's nodes with the ones encoded by MarshalBinary, see UnmarshalJSON
+func (r *Ring) UnmarshalBinary(b []byte) error {
+	d, err := decodeRing(b)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if r.members == nil {
+		r.init()
+	}
+	loaded, err := r.prepare(d)
+	if err != nil {
+		return err
+	}
+	r.restore(loaded)
+	return nil
+}
+
+// hasherProbes are the IDs hasherName hashes to check that the ring's Hasher func is the named hasher
+var hasherProbes = []string{"", "chring", "user_1", "node 1"}
+
+// hasherName names the ring's hasher, or returns "" when the ring's Hasher func was replaced directly instead of
+// set with WithHasher, as such a hasher cannot be named.
+func (r *Ring) hasherName() string {
+	h := r.hasher
+	if h == nil {
+		h = CRC32
+	}
+	for _, id := range hasherProbes {
+		if r.Hasher(id) != h.Hash32(id) {
+			return ""
+		}
+	}
+	return h.Name()
+}
+
+// export captures the ring's layout, with nodes sorted by ID. Callers must hold the lock.
+func (r *Ring) export() (ringData, error) {
+	d := ringData{Version: EncodingVersion, Hasher: r.hasherName(), Replicas: r.replicas, Probes: r.probes, Epoch: r.epoch}
+	if d.Hasher == "" {
+		return d, ErrUnnamedHasher
+	}
+	byID := make(map[string]*memberData, len(r.members))
+	for _, n := range r.current().nodes {
+		md, ok := byID[n.ID]
+		if !ok {
+			m := r.members[n.ID]
+			md = &memberData{ID: m.ID, Weight: m.Weight, Pinned: m.Pinned, Addr: m.Addr, Tags: m.Tags, State: r.states[m.ID]}
+			byID[n.ID] = md
+		}
+		if !pinnedAt(n.member, n.HashID) {
+			md.Positions = append(md.Positions, n.HashID)
+		}
+	}
+
+	d.Nodes = make([]memberData, 0, len(byID))
+	for _, md := range byID {
+		d.Nodes = append(d.Nodes, *md)
+	}
+	sort.Slice(d.Nodes, func(i, j int) bool { return d.Nodes[i].ID < d.Nodes[j].ID })
+	return d, nil
+}
+
+// pinnedAt reports whether the member was pinned at the position with AddAt
+func pinnedAt(m *member, hashID uint32) bool {
+	for _, p := range m.Pinned {
+		if p == hashID {
+			return true
+		}
+	}
+	return false
+}
+
+// prepare checks decoded ring data against the ring and builds the state to restore. Callers must hold the lock.
+func (r *Ring) prepare(d ringData) (*restored, error) {
+	if d.Version != EncodingVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	name := r.hasherName()
+	if name == "" {
+		return nil, ErrUnnamedHasher
+	}
+	if d.Hasher != name {
+		return nil, ErrHasherMismatch
+	}
+	if d.Replicas < 1 || d.Probes < 1 {
+		return nil, ErrCorrupt
+	}
+
+	loaded := &restored{
+		replicas: d.Replicas,
+		probes:   d.Probes,
+		epoch:    d.Epoch,
+		members:  make(map[string]*member, len(d.Nodes)),
+	}
+	for _, md := range d.Nodes {
+		if _, ok := loaded.members[md.ID]; ok || !validWeight(md.Weight) || md.State < Active || md.State > Down {
+			return nil, ErrCorrupt
+		}
+		if len(md.Positions)+len(md.Pinned) == 0 {
+			return nil, ErrCorrupt
+		}
+
+		m := &member{
+			ID:       md.ID,
+			Replicas: len(md.Positions),
+			Weight:   md.Weight,
+			Pinned:   append([]uint32(nil), md.Pinned...),
+			Addr:     md.Addr,
+			Tags:     md.Tags,
+		}
+		// a virtual node that is not at the hash of any of the member's replicas was moved off a collision
+		natural := make(map[uint32]bool, m.Replicas)
+		for i := 0; i < m.Replicas; i++ {
+			key := m.ID
+			if i > 0 {
+				key = m.ID + "#" + strconv.Itoa(i)
+			}
+			natural[r.Hasher(key)] = true
+		}
+		for _, hashID := range md.Positions {
+			m.moved = m.moved || !natural[hashID]
+			loaded.nodes = append(loaded.nodes, &node{ID: m.ID, HashID: hashID, member: m})
+		}
+		for _, hashID := range m.Pinned {
+			loaded.nodes = append(loaded.nodes, &node{ID: m.ID, HashID: hashID, member: m})
+		}
+		loaded.members[m.ID] = m
+
+		if md.State != Active {
+			if loaded.states == nil {
+				loaded.states = make(map[string]NodeState)
+			}
+			loaded.states[m.ID] = md.State
+		}
+	}
+	sort.Sort(loaded.nodes)
+	return loaded, nil
+}
+
+// restore replaces the ring's state with a prepared one, publishes it and sends a RingLoaded event. The ring takes
+// the epoch it was encoded with, unless it is already past it, as epochs never go backwards. Callers must hold the
+// lock.
+func (r *Ring) restore(loaded *restored) {
+	r.replicas = loaded.replicas
+	r.probes = loaded.probes
+	r.members = loaded.members
+	r.states = loaded.states
+	r.loads = make(map[string]int)
+	r.assignments = make(map[string]*assignment)
+	r.totalLoad = 0
+	if loaded.epoch > r.epoch+1 {
+		r.epoch = loaded.epoch - 1
+	}
+	old := r.publish(loaded.nodes)
+	r.notify(RingLoaded, "", old)
+}
+
+// appendBinary appends the binary encoding of the ring data to b
+func (d ringData) appendBinary(b []byte) []byte {
+	start := len(b)
+	b = append(b, ringMagic...)
+	b = append(b, byte(d.Version))
+	b = appendString(b, d.Hasher)
+	b = binary.AppendUvarint(b, uint64(d.Replicas))
+	b = binary.AppendUvarint(b, uint64(d.Probes))
+	b = binary.AppendUvarint(b, d.Epoch)
+	b = binary.AppendUvarint(b, uint64(len(d.Nodes)))
+	for _, n := range d.Nodes {
+		b = appendString(b, n.ID)
+		b = binary.BigEndian.AppendUint64(b, math.Float64bits(n.Weight))
+		b = appendPositions(b, n.Positions)
+		b = appendPositions(b, n.Pinned)
+		b = appendString(b, n.Addr)
+		keys := make([]string, 0, len(n.Tags))
+		for k := range n.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = binary.AppendUvarint(b, uint64(len(keys)))
+		for _, k := range keys {
+			b = appendString(appendString(b, k), n.Tags[k])
+		}
+		b = append(b, byte(n.State))
+	}
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b[start:]))
+}
+
+// decodeRing decodes ring data written by appendBinary
+func decodeRing(b []byte) (ringData, error) {
+	var d ringData
+	dec, err := newDecoder(b, ringMagic)
+	if err != nil {
+		return d, err
+	}
+
+	d.Version = EncodingVersion
+	d.Hasher = dec.string()
+	d.Replicas = dec.int()
+	d.Probes = dec.int()
+	d.Epoch = dec.uvarint()
+	d.Nodes = make([]memberData, dec.count(1))
+	for i := range d.Nodes {
+		n := &d.Nodes[i]
+		n.ID = dec.string()
+		n.Weight = math.Float64frombits(dec.uint64())
+		n.Positions = dec.positions()
+		n.Pinned = dec.positions()
+		n.Addr = dec.string()
+		if tags := dec.count(2); tags > 0 {
+			n.Tags = make(map[string]string, tags)
+			for j := 0; j < tags; j++ {
+				k := dec.string()
+				n.Tags[k] = dec.string()
+			}
+		}
+		n.State = NodeState(dec.byte())
+	}
+	return d, dec.done()
+}
+
+// managerData is a ring manager as it is encoded
+type managerData struct {
+	Version   int            `json:"version"`
+	Nodes     ringData       `json:"nodes"`
+	Keys      ringData       `json:"keys"`
+	Redirects []redirectData `json:"redirects,omitempty"`
+}
+
+// redirectData is a key placed away from a draining node
+type redirectData struct {
+	Key  string `json:"key"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MarshalJSON encodes the manager's node ring and its in-memory keys, see Ring.MarshalJSON
+func (rm *RingManager) MarshalJSON() ([]byte, error) {
+	rm.Lock()
+	defer rm.Unlock()
+	d, err := rm.export()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(d)
+}
+
+// UnmarshalJSON replaces the manager's nodes and keys with the ones encoded by MarshalJSON. The manager must have
+// been created with NewRingManager using the hasher the data was encoded with. Nothing changes if loading fails.
+func (rm *RingManager) UnmarshalJSON(b []byte) error {
+	var d managerData
+	if err := json.Unmarshal(b, &d); err != nil {
+		return err
+	}
+	return rm.restore(d)
+}
+
+// MarshalBinary encodes the manager in a compact binary format, see Ring.MarshalBinary
+func (rm *RingManager) MarshalBinary() ([]byte, error) {
+	rm.Lock()
+	defer rm.Unlock()
+
+	d, err := rm.export()
+	if err != nil {
+		return nil, err
+	}
+	b := append([]byte(nil), managerMagic...)
+	b = append(b, byte(d.Version))
+	ring := d.Nodes.appendBinary(nil)
+	b = append(binary.AppendUvarint(b, uint64(len(ring))), ring...)
+	ring = d.Keys.appendBinary(nil)
+	b = append(binary.AppendUvarint(b, uint64(len(ring))), ring...)
+	b = binary.AppendUvarint(b, uint64(len(d.Redirects)))
+	for _, rd := range d.Redirects {
+		b = appendString(appendString(appendString(b, rd.Key), rd.From), rd.To)
+	}
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(b)), nil
+}
+
+// UnmarshalBinary replaces the manager's nodes and keys with the ones encoded by MarshalBinary, see UnmarshalJSON
+func (rm *RingManager) UnmarshalBinary(b []byte) error {
+	dec, err := newDecoder(b, managerMagic)
+	if err != nil {
+		return err
+	}
+
+	d := managerData{Version: EncodingVersion}
+	if d.Nodes, err = decodeRing(dec.bytes()); err != nil {
+		return err
+	}
+	if d.Keys, err = decodeRing(dec.bytes()); err != nil {
+		return err
+	}
+	d.Redirects = make([]redirectData, dec.count(3))
+	for i := range d.Redirects {
+		d.Redirects[i] = redirectData{Key: dec.string(), From: dec.string(), To: dec.string()}
+	}
+	if err := dec.done(); err != nil {
+		return err
+	}
+	return rm.restore(d)
+}
+
+// export captures the manager's rings and redirects. Callers must hold the lock.
+func (rm *RingManager) export() (managerData, error) {
+	var err error
+	d := managerData{Version: EncodingVersion}
+	rm.nodeRing.Lock()
+	d.Nodes, err = rm.nodeRing.export()
+	rm.nodeRing.Unlock()
+	if err != nil {
+		return d, err
+	}
+	rm.dataRing.Lock()
+	d.Keys, err = rm.dataRing.export()
+	rm.dataRing.Unlock()
+	if err != nil {
+		return d, err
+	}
+
+	for key, rd := range rm.redirects {
+		d.Redirects = append(d.Redirects, redirectData{Key: key, From: rd.from, To: rd.to})
+	}
+	sort.Slice(d.Redirects, func(i, j int) bool { return d.Redirects[i].Key < d.Redirects[j].Key })
+	return d, nil
+}
+
+// restore replaces the manager's rings and redirects once both rings have been checked
+func (rm *RingManager) restore(d managerData) error {
+	if d.Version != EncodingVersion {
+		return ErrUnsupportedVersion
+	}
+
+	rm.Lock()
+	defer rm.Unlock()
+	rm.nodeRing.Lock()
+	defer rm.nodeRing.Unlock()
+	rm.dataRing.Lock()
+	defer rm.dataRing.Unlock()
+
+	nodeRing, err := rm.nodeRing.prepare(d.Nodes)
+	if err != nil {
+		return err
+	}
+	dataRing, err := rm.dataRing.prepare(d.Keys)
+	if err != nil {
+		return err
+	}
+	rm.nodeRing.restore(nodeRing)
+	rm.dataRing.restore(dataRing)
+
+	rm.redirects = make(map[string]redirect, len(d.Redirects))
+	for _, rd := range d.Redirects {
+		rm.redirects[rd.Key] = redirect{from: rd.From, to: rd.To}
+	}
+	return nil
+}
+
+// appendString appends a length prefixed string to b
+func appendString(b []byte, s string) []byte {
+	return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+}
+
+// appendPositions appends a count prefixed list of hash positions to b
+func appendPositions(b []byte, positions []uint32) []byte {
+	b = binary.AppendUvarint(b, uint64(len(positions)))
+	for _, p := range positions {
+		b = binary.BigEndian.AppendUint32(b, p)
+	}
+	return b
+}
+
+// decoder reads a binary encoding, remembering the first error so that callers only check once at the end
+type decoder struct {
+	b   []byte
+	err error
+}
+
+// newDecoder checks the magic number, version and checksum of an encoding and returns a decoder for its body
+func newDecoder(b []byte, magic []byte) (*decoder, error) {
+	if len(b) < len(magic)+1+4 || string(b[:len(magic)]) != string(magic) {
+		return nil, ErrCorrupt
+	}
+	if b[len(magic)] != EncodingVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	body, sum := b[:len(b)-4], binary.BigEndian.Uint32(b[len(b)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, ErrCorrupt
+	}
+	return &decoder{b: body[len(magic)+1:]}, nil
+}
+
+// done returns the first error met, or ErrCorrupt if bytes are left over
+func (d *decoder) done() error {
+	if d.err == nil && len(d.b) != 0 {
+		d.err = ErrCorrupt
+	}
+	return d.err
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = ErrCorrupt
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// int reads a uvarint that must fit an int
+func (d *decoder) int() int {
+	v := d.uvarint()
+	if v > math.MaxInt32 {
+		d.err = ErrCorrupt
+		return 0
+	}
+	return int(v)
+}
+
+// count reads the number of items that follow, each taking at least size bytes, so that corrupt counts cannot
+// cause huge allocations
+func (d *decoder) count(size int) int {
+	n := d.int()
+	if n*size > len(d.b) {
+		d.err = ErrCorrupt
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.b) {
+		d.err = ErrCorrupt
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+// bytes reads a length prefixed byte slice
+func (d *decoder) bytes() []byte {
+	return d.next(d.count(1))
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) byte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) positions() []uint32 {
+	n := d.count(4)
+	if n == 0 {
+		return nil
+	}
+	positions := make([]uint32, n)
+	for i := range positions {
+		positions[i] = binary.BigEndian.Uint32(d.next(4))
+	}
+	return positions
+}
@@ -0,0 +1,241 @@
+package chring_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func newEncodedRing(t *testing.T) *chring.Ring {
+	ring := chring.NewRing(chring.WithReplicas(10), chring.WithProbes(3))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	ring.AddNode(chring.Member{ID: "cache", Addr: "10.0.0.1:6379", Tags: map[string]string{"zone": "a"}})
+	if err := ring.AddWeighted("big", 2.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.AddAt("pinned", 1000, 2000000000); err != nil {
+		t.Fatal(err)
+	}
+	ring.Add(collidingLow)
+	ring.Add(collidingHigh)
+	if err := ring.SetState("node 2", chring.Down); err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+func assertSameRing(t *testing.T, got, want *chring.Ring) {
+	t.Helper()
+	if got.Snapshot().Checksum() != want.Snapshot().Checksum() {
+		t.Error("got a different layout after loading the ring")
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key %d", i)
+		if got.Get(key) != want.Get(key) {
+			t.Fatalf("got %q for %q, want %q", got.Get(key), key, want.Get(key))
+		}
+	}
+	for _, id := range want.Nodes() {
+		gw, _ := got.Weight(id)
+		ww, _ := want.Weight(id)
+		gs, _ := got.State(id)
+		ws, _ := want.State(id)
+		gm, _ := got.Member(id)
+		wm, _ := want.Member(id)
+		if gw != ww || gs != ws || fmt.Sprint(gm) != fmt.Sprint(wm) {
+			t.Errorf("got weight %v, state %s and %+v for %q, want weight %v, state %s and %+v", gw, gs, gm, id, ww, ws, wm)
+		}
+	}
+}
+
+func TestRingJSON(t *testing.T) {
+	ring := newEncodedRing(t)
+	data, err := json.Marshal(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := chring.NewRing()
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatal(err)
+	}
+	assertSameRing(t, loaded, ring)
+
+	// loaded rings keep working like the original
+	ring.Add("node 5")
+	loaded.Add("node 5")
+	_ = ring.Remove(collidingLow)
+	_ = loaded.Remove(collidingLow)
+	assertSameRing(t, loaded, ring)
+
+	wrongHasher := chring.NewRing(chring.WithHasher(chring.XXHash))
+	if err := json.Unmarshal(data, wrongHasher); err != chring.ErrHasherMismatch {
+		t.Errorf("got error %v, want %v", err, chring.ErrHasherMismatch)
+	}
+	if len(wrongHasher.Nodes()) != 0 {
+		t.Error("got nodes in a ring that failed to load")
+	}
+
+	future := bytes.Replace(data, []byte(`"version":1`), []byte(`"version":2`), 1)
+	if err := json.Unmarshal(future, chring.NewRing()); err != chring.ErrUnsupportedVersion {
+		t.Errorf("got error %v, want %v", err, chring.ErrUnsupportedVersion)
+	}
+}
+
+func TestRingBinary(t *testing.T) {
+	ring := newEncodedRing(t)
+	data, err := ring.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, _ := json.Marshal(ring)
+	if len(data) >= len(jsonData) {
+		t.Errorf("got %d bytes, want fewer than the %d bytes of JSON", len(data), len(jsonData))
+	}
+
+	loaded := chring.NewRing()
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	assertSameRing(t, loaded, ring)
+
+	if err := chring.NewRing(chring.WithHasher(chring.FNV1a)).UnmarshalBinary(data); err != chring.ErrHasherMismatch {
+		t.Errorf("got error %v, want %v", err, chring.ErrHasherMismatch)
+	}
+
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(corrupt)/2] ^= 0xff
+	if err := chring.NewRing().UnmarshalBinary(corrupt); err != chring.ErrCorrupt {
+		t.Errorf("got error %v for a damaged encoding, want %v", err, chring.ErrCorrupt)
+	}
+	if err := chring.NewRing().UnmarshalBinary(data[:len(data)-1]); err != chring.ErrCorrupt {
+		t.Errorf("got error %v for a truncated encoding, want %v", err, chring.ErrCorrupt)
+	}
+
+	future := append([]byte(nil), data...)
+	future[3] = 2
+	if err := chring.NewRing().UnmarshalBinary(future); err != chring.ErrUnsupportedVersion {
+		t.Errorf("got error %v, want %v", err, chring.ErrUnsupportedVersion)
+	}
+}
+
+func TestRingHasherIdentity(t *testing.T) {
+	ring := chring.NewRing(chring.WithHasher(chring.NewSipHasher(1, 2)))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	data, err := ring.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chring.NewRing(chring.WithHasher(chring.NewSipHasher(1, 2))).UnmarshalBinary(data); err != nil {
+		t.Errorf("got error %v, want nil loading with the same SipHash key", err)
+	}
+	if err := chring.NewRing(chring.WithHasher(chring.NewSipHasher(3, 4))).UnmarshalBinary(data); err != chring.ErrHasherMismatch {
+		t.Errorf("got error %v, want %v loading with another SipHash key", err, chring.ErrHasherMismatch)
+	}
+
+	custom := chring.NewRing()
+	custom.Hasher = func(id string) uint32 { return uint32(len(id)) }
+	custom.Add("a")
+	if _, err := custom.MarshalBinary(); err != chring.ErrUnnamedHasher {
+		t.Errorf("got error %v, want %v encoding a ring with a replaced Hasher", err, chring.ErrUnnamedHasher)
+	}
+	if err := custom.UnmarshalBinary(data); err != chring.ErrUnnamedHasher {
+		t.Errorf("got error %v, want %v loading into a ring with a replaced Hasher", err, chring.ErrUnnamedHasher)
+	}
+}
+
+func TestRingLoadEpoch(t *testing.T) {
+	ring := newEncodedRing(t)
+	data, err := ring.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := chring.NewRing(chring.WithReplicas(10))
+	loaded.Add("node 1")
+	events, cancel := loaded.Subscribe()
+	defer cancel()
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	want := ring.Snapshot()
+	if got := loaded.Snapshot(); got.Epoch() != want.Epoch() || got.Checksum() != want.Checksum() {
+		t.Errorf("got epoch %d and checksum %d, want %d and %d as encoded", got.Epoch(), got.Checksum(), want.Epoch(), want.Checksum())
+	}
+	event := <-events
+	if event.Type != chring.RingLoaded || event.Epoch != want.Epoch() || len(event.Migrations) == 0 {
+		t.Errorf("got %s event at epoch %d with %d migrations, want %s at %d with the ranges that moved", event.Type, event.Epoch, len(event.Migrations), chring.RingLoaded, want.Epoch())
+	}
+
+	// epochs never go backwards, even when loading a ring encoded earlier
+	for i := 0; i < 100; i++ {
+		loaded.Add(fmt.Sprintf("extra %d", i))
+	}
+	past := loaded.Snapshot().Epoch()
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Snapshot().Epoch(); got != past+1 {
+		t.Errorf("got epoch %d loading an older ring, want %d", got, past+1)
+	}
+}
+
+func TestRingManagerEncoding(t *testing.T) {
+	ringManager := chring.NewRingManager(chring.WithHasher(chring.Murmur3))
+	for _, n := range NodeList {
+		_ = ringManager.AddNode(n)
+	}
+	for i := 0; i < 50; i++ {
+		_ = ringManager.AddKey(fmt.Sprintf("user %d", i))
+	}
+	_ = ringManager.SetNodeState("node 3", chring.Draining)
+	for i := 50; i < 100; i++ {
+		_ = ringManager.AddKey(fmt.Sprintf("user %d", i))
+	}
+
+	jsonData, err := json.Marshal(ringManager)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryData, err := ringManager.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromJSON := chring.NewRingManager(chring.WithHasher(chring.Murmur3))
+	if err := json.Unmarshal(jsonData, fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	fromBinary := chring.NewRingManager(chring.WithHasher(chring.Murmur3))
+	if err := fromBinary.UnmarshalBinary(binaryData); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, loaded := range []*chring.RingManager{fromJSON, fromBinary} {
+		for _, n := range NodeList {
+			got, _ := loaded.GetKeys(n)
+			want, _ := ringManager.GetKeys(n)
+			var gotIDs, wantIDs []string
+			for _, k := range got {
+				gotIDs = append(gotIDs, k.ID)
+			}
+			for _, k := range want {
+				wantIDs = append(wantIDs, k.ID)
+			}
+			if fmt.Sprint(gotIDs) != fmt.Sprint(wantIDs) {
+				t.Errorf("got keys %v for %q, want %v", gotIDs, n, wantIDs)
+			}
+		}
+	}
+
+	if err := chring.NewRingManager().UnmarshalBinary(binaryData); err != chring.ErrHasherMismatch {
+		t.Errorf("got error %v, want %v", err, chring.ErrHasherMismatch)
+	}
+}
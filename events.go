@@ -0,0 +1,101 @@
+package chring
+
+// EventBufferSize is how many events a subscriber may fall behind before events are dropped
+const EventBufferSize = 64
+
+// EventType tells what kind of change a RingEvent reports
+type EventType int
+
+const (
+	// NodeAdded is sent when a node joins the ring, or when an existing node is pinned at more positions
+	NodeAdded EventType = iota + 1
+	// NodeRemoved is sent when a node leaves the ring
+	NodeRemoved
+	// WeightChanged is sent when a node's weight is changed
+	WeightChanged
+	// StateChanged is sent when a node is set active, draining or down. Setting a node down or back up moves the
+	// ranges it owns, while draining moves none.
+	StateChanged
+	// ChangesApplied is sent once for a ChangeSet given to Apply, with the migrations of all of its changes
+	// combined. Its Node is empty.
+	ChangesApplied
+	// RingLoaded is sent when UnmarshalJSON or UnmarshalBinary replaces the ring, with the migrations between the
+	// old and loaded layouts. Its Node is empty, and its Epoch is the loaded ring's, which may skip ahead.
+	RingLoaded
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case NodeAdded:
+		return "NodeAdded"
+	case NodeRemoved:
+		return "NodeRemoved"
+	case WeightChanged:
+		return "WeightChanged"
+	case StateChanged:
+		return "StateChanged"
+	case ChangesApplied:
+		return "ChangesApplied"
+	case RingLoaded:
+		return "RingLoaded"
+	}
+	return "Unknown"
+}
+
+// RingEvent reports a change to the ring's membership
+type RingEvent struct {
+	Type EventType
+	Node string
+	// Epoch is the epoch of the ring's view once the change was made. Epochs of consecutive events increase by one,
+	// so a gap means events were dropped, except before a RingLoaded event, which carries the loaded ring's epoch.
+	Epoch uint64
+	// Migrations are the hash ranges that changed owner, as reported by Diff
+	Migrations []Migration
+}
+
+// Subscribe returns a channel receiving an event for every change to the ring's membership, and a function to
+// cancel the subscription and close the channel. Delivery never blocks changes to the ring: each subscriber has a
+// buffer of EventBufferSize events, and events that arrive while the buffer is full are dropped for that subscriber.
+// A slow consumer notices drops as gaps in the event epochs, and should then resynchronize from Snapshot.
+func (r *Ring) Subscribe() (<-chan RingEvent, func()) {
+	r.Lock()
+	defer r.Unlock()
+
+	id := r.nextSub
+	r.nextSub++
+	events := make(chan RingEvent, EventBufferSize)
+	r.subscribers[id] = events
+
+	cancel := func() {
+		r.Lock()
+		defer r.Unlock()
+		if _, ok := r.subscribers[id]; ok {
+			delete(r.subscribers, id)
+			close(events)
+		}
+	}
+	return events, cancel
+}
+
+// notify sends an event for a change that replaced the old view to every subscriber whose buffer has room.
+// Callers must hold the lock.
+func (r *Ring) notify(t EventType, id string, old *RingView) {
+	if len(r.subscribers) == 0 {
+		return
+	}
+
+	current := r.current()
+	r.send(RingEvent{Type: t, Node: id, Epoch: current.epoch, Migrations: diff(old, current)})
+}
+
+// send delivers the event to every subscriber whose buffer has room. Callers must hold the lock.
+func (r *Ring) send(event RingEvent) {
+	for _, events := range r.subscribers {
+		select {
+		case events <- event:
+		default:
+			debugf("dropping %s event for %q, subscriber is full", event.Type, event.Node)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestSubscribe(t *testing.T) {
+	ring := newSeededRing()
+	events, cancel := ring.Subscribe()
+
+	before := ring.Snapshot()
+	ring.Add("node 5")
+	ring.Add("node 5") // no change, no event
+	_ = ring.SetWeight("node 5", 3)
+	_ = ring.Remove("node 1")
+
+	want := []struct {
+		Type chring.EventType
+		Node string
+	}{
+		{chring.NodeAdded, "node 5"},
+		{chring.WeightChanged, "node 5"},
+		{chring.NodeRemoved, "node 1"},
+	}
+	for i, w := range want {
+		event := <-events
+		if event.Type != w.Type || event.Node != w.Node {
+			t.Errorf("got %s for %q, want %s for %q", event.Type, event.Node, w.Type, w.Node)
+		}
+		if got := before.Epoch() + uint64(i) + 1; event.Epoch != got {
+			t.Errorf("got epoch %d, want %d", event.Epoch, got)
+		}
+		if len(event.Migrations) == 0 {
+			t.Errorf("got no migrations for %s of %q", event.Type, event.Node)
+		}
+	}
+
+	cancel()
+	cancel() // cancelling twice is harmless
+	if _, ok := <-events; ok {
+		t.Error("got an event after cancelling, want the channel closed")
+	}
+	ring.Add("node 6") // must not panic sending to a cancelled subscriber
+}
+
+func TestSubscribeDropsForSlowConsumers(t *testing.T) {
+	ring := chring.NewRing()
+	events, cancel := ring.Subscribe()
+	defer cancel()
+
+	for i := 0; i < chring.EventBufferSize+10; i++ {
+		ring.Add(fmt.Sprintf("node %d", i))
+	}
+	if got := len(events); got != chring.EventBufferSize {
+		t.Errorf("got %d buffered events, want %d", got, chring.EventBufferSize)
+	}
+	if got := len(ring.Nodes()); got != chring.EventBufferSize+10 {
+		t.Errorf("got %d nodes, want every change applied despite the full buffer", got)
+	}
+}
@@ -8,7 +8,8 @@ import (
 )
 
 func main() {
-	rm := chring.NewRingManager()
+	// sequential key names cluster with the default crc32 hasher, so spread them with xxhash
+	rm := chring.NewRingManager(chring.WithHasher(chring.XXHash))
 	for _, n := range []string{"123.45.83.190", "123.45.83.191", "123.45.83.192", "123.45.78.191", "123.45.78.189", "123.12.09.249"} {
 		rm.AddNode(n)
 	}
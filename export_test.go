@@ -0,0 +1,6 @@
+package chring
+
+// VirtualNodes returns the number of virtual nodes in the ring, for tests in chring_test
+func VirtualNodes(r *Ring) int {
+	return r.current().nodes.Len()
+}
@@ -0,0 +1,391 @@
+package chring
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"math/bits"
+)
+
+// Hasher places node IDs and keys in the ring's hash space. Any implementation must:
+//
+//   - be deterministic: the same input always hashes to the same value, across processes, platforms and restarts
+//   - be safe for concurrent use
+//   - spread inputs evenly over the whole uint32 space, including inputs that only differ in a trailing
+//     character such as "user_1" and "user_2"
+//   - flip each bit of the hash about half the time between such inputs, so that nearby IDs do not cluster
+//
+// Name identifies the algorithm (and any parameters that change its output) so that rings built with different
+// hashers can be told apart. ValidateHasher checks a Hasher against this contract.
+type Hasher interface {
+	Name() string
+	Hash32(id string) uint32
+}
+
+// Hasher64 is a Hasher that can also place IDs in a 64 bit hash space, as used by Ring64. The same contract applies
+// over the whole uint64 space.
+type Hasher64 interface {
+	Hasher
+	Hash64(id string) uint64
+}
+
+// Built in hashers. All of them but CRC32 implement Hasher64. CRC32 is the default as it was the ring's original hasher, but it clusters sequential IDs,
+// so prefer XXHash or Murmur3 for new rings. CRC32 and FNV1a fail ValidateHasher for that reason.
+var (
+	CRC32   Hasher = crc32Hasher{}
+	FNV1a   Hasher = fnv1aHasher{}
+	Murmur3 Hasher = murmur3Hasher{}
+	XXHash  Hasher = xxHasher{}
+)
+
+// WithHasher sets the hasher used to place nodes and keys in the ring
+func WithHasher(h Hasher) Option {
+	return func(c *config) {
+		c.hasher = h
+	}
+}
+
+// hash64 returns the 64 bit form of a hasher, widening the 32 bit hash of hashers that do not implement Hasher64.
+// A nil hasher hashes with xxHash.
+func hash64(h Hasher) func(id string) uint64 {
+	if h == nil {
+		return xxHasher{}.Hash64
+	}
+	if h64, ok := h.(Hasher64); ok {
+		return h64.Hash64
+	}
+	return func(id string) uint64 { return uint64(h.Hash32(id)) }
+}
+
+// NewSipHasher returns a keyed SipHash-2-4 hasher. Keep the key secret when the keys being routed come from
+// untrusted clients, so they cannot craft keys that all land on the same node.
+func NewSipHasher(k0, k1 uint64) Hasher {
+	return sipHasher{k0: k0, k1: k1, name: fmt.Sprintf("siphash-%016x", sipHashSum64(k0, k1, []byte(sipFingerprint)))}
+}
+
+// sipFingerprint is hashed under a SipHash key to name the hasher. The name tells keys apart without revealing them.
+const sipFingerprint = "chring key fingerprint"
+
+// ErrHasherNotDeterministic is returned by ValidateHasher when the same input hashes to different values
+var ErrHasherNotDeterministic = errors.New("hasher is not deterministic")
+
+// ValidateHasher checks a hasher against the Hasher contract by hashing a run of sequential IDs. It returns an error
+// if the hasher is not deterministic, if it produces many collisions, if it leaves parts of the hash space over or
+// under populated, or if some bit of the hash flips much more or less than half the time between consecutive IDs.
+func ValidateHasher(h Hasher) error {
+	const samples, buckets = 20000, 32
+
+	seen := make(map[uint32]bool, samples)
+	counts := make([]int, buckets)
+	var flips [32]int
+	var prev uint32
+	collisions := 0
+	for i := 0; i < samples; i++ {
+		id := fmt.Sprintf("user_%d", i)
+		hashID := h.Hash32(id)
+		if h.Hash32(id) != hashID {
+			return ErrHasherNotDeterministic
+		}
+		if seen[hashID] {
+			collisions++
+		}
+		seen[hashID] = true
+		counts[hashID/(1<<32/buckets)]++
+		if i > 0 {
+			for bit, diff := 0, hashID^prev; diff != 0; bit, diff = bit+1, diff>>1 {
+				flips[bit] += int(diff & 1)
+			}
+		}
+		prev = hashID
+	}
+
+	// a good 32 bit hash collides about samples^2 / 2^33 times, so 0.05 collisions for 20000 samples
+	if collisions > samples/1000 {
+		return fmt.Errorf("hasher %q produced %d collisions for %d sequential IDs", h.Name(), collisions, samples)
+	}
+	expected := samples / buckets
+	for i, count := range counts {
+		if count < expected/2 || count > expected*3/2 {
+			return fmt.Errorf("hasher %q placed %d of %d sequential IDs in bucket %d, want about %d", h.Name(), count, samples, i, expected)
+		}
+	}
+	// a good hash flips each bit for half of the pairs, give or take half a percent at this sample size
+	for bit, n := range flips {
+		if rate := float64(n) / (samples - 1); rate < 0.45 || rate > 0.55 {
+			return fmt.Errorf("hasher %q flipped bit %d of the hash between %.0f%% of consecutive IDs, want about 50%%", h.Name(), bit, 100*rate)
+		}
+	}
+	return nil
+}
+
+type crc32Hasher struct{}
+
+func (crc32Hasher) Name() string            { return "crc32" }
+func (crc32Hasher) Hash32(id string) uint32 { return crc32.ChecksumIEEE([]byte(id)) }
+
+type fnv1aHasher struct{}
+
+func (fnv1aHasher) Name() string { return "fnv1a" }
+func (fnv1aHasher) Hash32(id string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return h.Sum32()
+}
+func (fnv1aHasher) Hash64(id string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return h.Sum64()
+}
+
+type murmur3Hasher struct{}
+
+func (murmur3Hasher) Name() string            { return "murmur3" }
+func (murmur3Hasher) Hash32(id string) uint32 { return murmur3Sum32([]byte(id), 0) }
+func (murmur3Hasher) Hash64(id string) uint64 {
+	h1, _ := murmur3Sum128([]byte(id), 0)
+	return h1
+}
+
+type xxHasher struct{}
+
+func (xxHasher) Name() string            { return "xxhash" }
+func (xxHasher) Hash32(id string) uint32 { return uint32(xxhashSum64([]byte(id), 0)) }
+func (xxHasher) Hash64(id string) uint64 { return xxhashSum64([]byte(id), 0) }
+
+type sipHasher struct {
+	k0, k1 uint64
+	name   string
+}
+
+func (s sipHasher) Name() string            { return s.name }
+func (s sipHasher) Hash32(id string) uint32 { return uint32(sipHashSum64(s.k0, s.k1, []byte(id))) }
+func (s sipHasher) Hash64(id string) uint64 { return sipHashSum64(s.k0, s.k1, []byte(id)) }
+
+// murmur3Sum32 is MurmurHash3 x86_32
+func murmur3Sum32(data []byte, seed uint32) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+
+	h := seed
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[n*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// murmur3Sum128 is MurmurHash3 x64_128
+func murmur3Sum128(data []byte, seed uint64) (uint64, uint64) {
+	const c1, c2 = 0x87c37b91114253d5, 0x4cf5ad432745937f
+
+	h1, h2 := seed, seed
+	n := len(data) / 16
+	for i := 0; i < n; i++ {
+		k1 := binary.LittleEndian.Uint64(data[i*16:])
+		k2 := binary.LittleEndian.Uint64(data[i*16+8:])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	tail := data[n*16:]
+	var k1, k2 uint64
+	for i := len(tail) - 1; i >= 8; i-- {
+		k2 ^= uint64(tail[i]) << (8 * uint(i-8))
+	}
+	if len(tail) > 8 {
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+	}
+	for i := min(len(tail), 8) - 1; i >= 0; i-- {
+		k1 ^= uint64(tail[i]) << (8 * uint(i))
+	}
+	if len(tail) > 0 {
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= uint64(len(data))
+	h2 ^= uint64(len(data))
+	h1 += h2
+	h2 += h1
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+	h1 += h2
+	h2 += h1
+	return h1, h2
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxhashSum64 is XXH64
+func xxhashSum64(b []byte, seed uint64) uint64 {
+	n := len(b)
+	var h uint64
+
+	if n >= 32 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for ; len(b) >= 32; b = b[32:] {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(b[0:]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(b[8:]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(b[16:]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(b[24:]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+
+	h += uint64(n)
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for ; len(b) > 0; b = b[1:] {
+		h ^= uint64(b[0]) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// sipHashSum64 is SipHash-2-4 with a 128 bit key split into k0 and k1
+func sipHashSum64(k0, k1 uint64, b []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(b)
+	for ; len(b) >= 8; b = b[8:] {
+		m := binary.LittleEndian.Uint64(b)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+
+	m := uint64(n) << 56
+	for i := len(b) - 1; i >= 0; i-- {
+		m |= uint64(b[i]) << (8 * uint(i))
+	}
+	v3 ^= m
+	round()
+	round()
+	v0 ^= m
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
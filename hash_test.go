@@ -0,0 +1,72 @@
+package chring
+
+import (
+	"testing"
+)
+
+func TestHasherVectors(t *testing.T) {
+	if got, want := murmur3Sum32([]byte("hello"), 0), uint32(0x248bfa47); got != want {
+		t.Errorf("murmur3: got %#x, want %#x", got, want)
+	}
+	if got, want := murmur3Sum32([]byte("The quick brown fox jumps over the lazy dog"), 0), uint32(0x2e4ff723); got != want {
+		t.Errorf("murmur3: got %#x, want %#x", got, want)
+	}
+	if h1, h2 := murmur3Sum128([]byte("hello"), 0); h1 != 0xcbd8a7b341bd9b02 || h2 != 0x5b1e906a48ae1d19 {
+		t.Errorf("murmur3 x64_128: got %#x %#x, want 0xcbd8a7b341bd9b02 0x5b1e906a48ae1d19", h1, h2)
+	}
+	if got, want := xxhashSum64([]byte(""), 0), uint64(0xef46db3751d8e999); got != want {
+		t.Errorf("xxhash: got %#x, want %#x", got, want)
+	}
+	if got, want := xxhashSum64([]byte("abc"), 0), uint64(0x44bc2cf5ad770999); got != want {
+		t.Errorf("xxhash: got %#x, want %#x", got, want)
+	}
+
+	// reference vectors from the SipHash paper use the key 00 01 02 ... 0f and messages 00 01 02 ...
+	msg := make([]byte, 15)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	k0, k1 := uint64(0x0706050403020100), uint64(0x0f0e0d0c0b0a0908)
+	if got, want := sipHashSum64(k0, k1, nil), uint64(0x726fdb47dd0e0e31); got != want {
+		t.Errorf("siphash: got %#x, want %#x", got, want)
+	}
+	if got, want := sipHashSum64(k0, k1, msg), uint64(0xa129ca6149be45e5); got != want {
+		t.Errorf("siphash: got %#x, want %#x", got, want)
+	}
+}
+
+func TestValidateHasher(t *testing.T) {
+	for _, h := range []Hasher{Murmur3, XXHash, NewSipHasher(1, 2)} {
+		if err := ValidateHasher(h); err != nil {
+			t.Errorf("got error %v, want nil validating %q", err, h.Name())
+		}
+	}
+	// both cluster sequential IDs, as the contract forbids
+	for _, h := range []Hasher{CRC32, FNV1a} {
+		if err := ValidateHasher(h); err == nil {
+			t.Errorf("got no error, want one validating %q", h.Name())
+		}
+	}
+
+	if err := ValidateHasher(testHasher(func(string) uint32 { return 42 })); err == nil {
+		t.Error("got no error, want one validating a constant hasher")
+	}
+	var calls uint32
+	if err := ValidateHasher(testHasher(func(string) uint32 { calls++; return calls })); err != ErrHasherNotDeterministic {
+		t.Errorf("got error %v, want %v validating a counter", err, ErrHasherNotDeterministic)
+	}
+}
+
+func TestWithHasher(t *testing.T) {
+	r := NewRing(WithHasher(XXHash))
+	r.Add("Foo")
+	if got, want := r.current().nodes[0].HashID, XXHash.Hash32("Foo"); got != want {
+		t.Errorf("got hashID %d, want %d from the configured hasher", got, want)
+	}
+}
+
+// testHasher adapts a func to the Hasher interface
+type testHasher func(id string) uint32
+
+func (testHasher) Name() string              { return "test" }
+func (h testHasher) Hash32(id string) uint32 { return h(id) }
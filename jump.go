@@ -0,0 +1,88 @@
+package chring
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotLastNode is returned when removing any node but the most recently added one from a JumpRing
+var ErrNotLastNode = errors.New("only the last node can be removed")
+
+// JumpRing routes keys with Jump Consistent Hash (Lamping and Veach). It needs no memory beyond the node list and
+// balances keys perfectly, but nodes are numbered buckets: Add appends a bucket, and only the last bucket can be
+// removed. Use it for a fixed or growing number of shards rather than for arbitrary membership changes.
+type JumpRing struct {
+	sync.Mutex
+	nodes  []string
+	hasher func(id string) uint64
+}
+
+// NewJumpRing creates a new jump hash ring hashing keys with xxHash. Only WithHasher is honored; a hasher without a
+// 64 bit form is widened from its 32 bit hash.
+func NewJumpRing(opts ...Option) *JumpRing {
+	c := newConfig(opts)
+	return &JumpRing{hasher: hash64(c.hasher)}
+}
+
+// Add appends a new bucket for the node
+func (j *JumpRing) Add(id string) {
+	j.Lock()
+	defer j.Unlock()
+
+	// don't insert the same node more than once
+	for _, n := range j.nodes {
+		if n == id {
+			return
+		}
+	}
+	j.nodes = append(j.nodes, id)
+}
+
+// Remove takes the node out of the ring. Only the most recently added node can be removed; any other node returns
+// ErrNotLastNode, as renumbering buckets would move most keys.
+func (j *JumpRing) Remove(id string) error {
+	j.Lock()
+	defer j.Unlock()
+
+	for i, n := range j.nodes {
+		if n != id {
+			continue
+		}
+		if i != len(j.nodes)-1 {
+			return ErrNotLastNode
+		}
+		j.nodes = j.nodes[:i]
+		return nil
+	}
+	return ErrNotFound
+}
+
+// Get retrieves the node whose bucket the key jumps to
+func (j *JumpRing) Get(key string) string {
+	j.Lock()
+	defer j.Unlock()
+
+	if len(j.nodes) == 0 {
+		return ""
+	}
+	return j.nodes[jumpHash(j.hasher(key), len(j.nodes))]
+}
+
+// Nodes returns the ID of every node in bucket order
+func (j *JumpRing) Nodes() []string {
+	j.Lock()
+	defer j.Unlock()
+
+	return append([]string(nil), j.nodes...)
+}
+
+// jumpHash maps a key to one of the given number of buckets
+func jumpHash(key uint64, buckets int) int {
+	var b, next int64 = -1, 0
+	for next < int64(buckets) {
+		b = next
+		key = key*2862933555777941757 + 1
+		next = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,88 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestJumpRing(t *testing.T) {
+	ring := chring.NewJumpRing()
+	for i := 0; i < 10; i++ {
+		ring.Add(fmt.Sprintf("shard %d", i))
+	}
+	ring.Add("shard 0")
+	if got := len(ring.Nodes()); got != 10 {
+		t.Fatalf("got %d nodes, want 10", got)
+	}
+
+	before := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("user_%d", i)
+		before[key] = ring.Get(key)
+		counts[before[key]]++
+	}
+	for _, n := range ring.Nodes() {
+		if counts[n] < 900 || counts[n] > 1100 {
+			t.Errorf("got %d of 10000 keys on %q, want about 1000", counts[n], n)
+		}
+	}
+
+	// a new bucket only takes keys, it never shuffles them between the existing ones
+	ring.Add("shard 10")
+	moved := 0
+	for key, was := range before {
+		now := ring.Get(key)
+		if now != was {
+			moved++
+			if now != "shard 10" {
+				t.Fatalf("got %q for %q, want it to stay on %q or move to the new shard", now, key, was)
+			}
+		}
+	}
+	if moved < 700 || moved > 1100 {
+		t.Errorf("got %d keys moved, want about 10000/11", moved)
+	}
+
+	if err := ring.Remove("shard 3"); err != chring.ErrNotLastNode {
+		t.Errorf("got error %v, want %v removing a middle shard", err, chring.ErrNotLastNode)
+	}
+	if err := ring.Remove("shard x"); err != chring.ErrNotFound {
+		t.Errorf("got error %v, want %v removing an unknown shard", err, chring.ErrNotFound)
+	}
+	if err := ring.Remove("shard 10"); err != nil {
+		t.Fatalf("got error %v, want nil removing the last shard", err)
+	}
+	for key, was := range before {
+		if now := ring.Get(key); now != was {
+			t.Fatalf("got %q for %q, want %q back after removing the new shard", now, key, was)
+		}
+	}
+}
+
+func TestRouters(t *testing.T) {
+	routers := map[string]chring.Router{
+		"ring":   chring.NewRing(),
+		"ring64": chring.NewRing64(),
+		"jump":   chring.NewJumpRing(),
+	}
+	for name, router := range routers {
+		if got := router.Get("user A"); got != "" {
+			t.Errorf("%s: got %q, want an empty node from an empty router", name, got)
+		}
+		for _, n := range NodeList {
+			router.Add(n)
+		}
+		if got := router.Get("user A"); !In(got, NodeList) {
+			t.Errorf("%s: got %q for Get(%q)", name, got, "user A")
+		}
+		if got := len(router.Nodes()); got != len(NodeList) {
+			t.Errorf("%s: got %d nodes, want %d", name, got, len(NodeList))
+		}
+		if err := router.Remove(NodeList[len(NodeList)-1]); err != nil {
+			t.Errorf("%s: got error %v, want nil removing the last node", name, err)
+		}
+	}
+}
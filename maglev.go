@@ -0,0 +1,135 @@
+package chring
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// DefaultMaglevSize is a prime lookup table size suitable for up to a few hundred nodes. The table should be at least
+// 100 times larger than the number of nodes to keep every node's share within 1% of the others.
+const DefaultMaglevSize = 65537
+
+// ErrNotPrime is returned when a Maglev lookup table size is not a prime number
+var ErrNotPrime = errors.New("table size must be prime")
+
+// MaglevTable routes keys with Maglev hashing (Eisenbud et al.), filling a fixed size lookup table with the nodes of
+// a Ring so that every lookup is a single hash and index. Nodes get near equal shares of the table, and rebuilding
+// after a node is added or removed reassigns few entries beyond those the node gains or loses. Node weights are not
+// taken into account.
+type MaglevTable struct {
+	sync.Mutex
+	ring   *Ring
+	size   uint64
+	nodes  []string
+	lookup []int
+	hasher func(id string) uint64
+	epoch  uint64 // epoch of the ring view the table was built from
+}
+
+// NewMaglevTable creates a lookup table of the given prime size and fills it with the nodes of the ring
+func NewMaglevTable(r *Ring, size uint64) (*MaglevTable, error) {
+	if !big.NewInt(0).SetUint64(size).ProbablyPrime(0) {
+		return nil, ErrNotPrime
+	}
+	m := &MaglevTable{ring: r, size: size, hasher: hash64(r.hasher)}
+	m.Rebuild()
+	return m, nil
+}
+
+// Add inserts a new node into the underlying ring and rebuilds the table
+func (m *MaglevTable) Add(id string) {
+	m.ring.Add(id)
+	m.Rebuild()
+}
+
+// Remove takes the node out of the underlying ring and rebuilds the table
+func (m *MaglevTable) Remove(id string) error {
+	if err := m.ring.Remove(id); err != nil {
+		return err
+	}
+	m.Rebuild()
+	return nil
+}
+
+// Rebuild refills the table from the ring's current nodes, leaving out nodes that are down. Call it after changing
+// the ring directly, including after changing the state of a node. Concurrent rebuilds may finish in any order, but a table built from an older view of the ring never replaces
+// one built from a newer view.
+func (m *MaglevTable) Rebuild() {
+	v := m.ring.Snapshot()
+	var ids []string
+	for _, id := range v.Nodes() {
+		if v.State(id) != Down {
+			ids = append(ids, id)
+		}
+	}
+	// populate in a fixed order so that every process builds the same table
+	sort.Strings(ids)
+	lookup := m.populate(ids)
+
+	m.Lock()
+	defer m.Unlock()
+	if v.Epoch() <= m.epoch {
+		return
+	}
+	m.nodes = ids
+	m.lookup = lookup
+	m.epoch = v.Epoch()
+}
+
+// Get retrieves the node for the given key from the lookup table
+func (m *MaglevTable) Get(key string) string {
+	m.Lock()
+	defer m.Unlock()
+
+	if len(m.nodes) == 0 {
+		return ""
+	}
+	return m.nodes[m.lookup[m.hasher(key)%m.size]]
+}
+
+// Nodes returns the ID of every node in the table, sorted by ID
+func (m *MaglevTable) Nodes() []string {
+	m.Lock()
+	defer m.Unlock()
+
+	return append([]string(nil), m.nodes...)
+}
+
+// populate fills a lookup table by letting each node in turn claim the next free entry of its own permutation of
+// the table, until every entry is taken
+func (m *MaglevTable) populate(ids []string) []int {
+	lookup := make([]int, m.size)
+	if len(ids) == 0 {
+		return lookup
+	}
+	for i := range lookup {
+		lookup[i] = -1
+	}
+
+	offsets := make([]uint64, len(ids))
+	skips := make([]uint64, len(ids))
+	next := make([]uint64, len(ids))
+	for i, id := range ids {
+		offsets[i] = m.hasher(id) % m.size
+		skips[i] = m.hasher(id+"\x00skip")%(m.size-1) + 1
+	}
+
+	var filled uint64
+	for {
+		for i := range ids {
+			c := (offsets[i] + next[i]*skips[i]) % m.size
+			for lookup[c] >= 0 {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % m.size
+			}
+			lookup[c] = i
+			next[i]++
+			filled++
+			if filled == m.size {
+				return lookup
+			}
+		}
+	}
+}
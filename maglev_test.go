@@ -0,0 +1,135 @@
+package chring_test
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestMaglevConcurrentAdds(t *testing.T) {
+	ring := chring.NewRing()
+	table, err := chring.NewMaglevTable(ring, 251)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			table.Add(fmt.Sprintf("node %d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	want := ring.Nodes()
+	sort.Strings(want)
+	if got := table.Nodes(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got table nodes %v, want the ring's %v", got, want)
+	}
+}
+
+func TestMaglevTable(t *testing.T) {
+	if _, err := chring.NewMaglevTable(chring.NewRing(), 65536); err != chring.ErrNotPrime {
+		t.Errorf("got error %v, want %v for a table size that is not prime", err, chring.ErrNotPrime)
+	}
+
+	table, err := chring.NewMaglevTable(chring.NewRing(), chring.DefaultMaglevSize)
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	if got := table.Get("user A"); got != "" {
+		t.Errorf("got %q, want an empty node from an empty table", got)
+	}
+
+	var nodeList []string
+	for i := 0; i < 10; i++ {
+		nodeList = append(nodeList, fmt.Sprintf("10.0.0.%d", i))
+		table.Add(nodeList[i])
+	}
+
+	before := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 20000; i++ {
+		key := fmt.Sprintf("user_%d", i)
+		before[key] = table.Get(key)
+		counts[before[key]]++
+	}
+	for _, n := range nodeList {
+		if counts[n] < 1800 || counts[n] > 2200 {
+			t.Errorf("got %d of 20000 keys on %q, want about 2000", counts[n], n)
+		}
+	}
+
+	removed := nodeList[3]
+	if err := table.Remove(removed); err != nil {
+		t.Fatalf("got error %v, want nil removing a node", err)
+	}
+	disrupted := 0
+	for key, was := range before {
+		now := table.Get(key)
+		if now == removed {
+			t.Fatalf("got %q for %q after it was removed", now, key)
+		}
+		if was != removed && now != was {
+			disrupted++
+		}
+	}
+	// the paper reports a few percent of entries shuffled between the remaining nodes
+	if disrupted > 1000 {
+		t.Errorf("got %d of 20000 keys moved between remaining nodes, want few", disrupted)
+	}
+}
+
+func TestMaglevSkipsDownNodes(t *testing.T) {
+	ring := chring.NewRing()
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	table, err := chring.NewMaglevTable(ring, chring.DefaultMaglevSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ring.SetState("node 1", chring.Down); err != nil {
+		t.Fatal(err)
+	}
+	table.Rebuild()
+	for i := 0; i < 1000; i++ {
+		if got := table.Get(fmt.Sprintf("user_%d", i)); got == "node 1" {
+			t.Fatalf("got down node %q for a key", got)
+		}
+	}
+	if got, want := len(table.Nodes()), len(NodeList)-1; got != want {
+		t.Errorf("got %d nodes in the table, want %d without the down one", got, want)
+	}
+}
+
+func BenchmarkMaglevGet(b *testing.B) {
+	ring := chring.NewRing(chring.WithReplicas(160))
+	for i := 0; i < 100; i++ {
+		ring.Add(fmt.Sprintf("10.0.0.%d", i))
+	}
+	table, _ := chring.NewMaglevTable(ring, chring.DefaultMaglevSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		table.Get("user_12345")
+	}
+}
+
+func BenchmarkRingGet(b *testing.B) {
+	ring := chring.NewRing(chring.WithReplicas(160))
+	for i := 0; i < 100; i++ {
+		ring.Add(fmt.Sprintf("10.0.0.%d", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ring.Get("user_12345")
+	}
+}
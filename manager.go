@@ -2,6 +2,7 @@ package chring
 
 import (
 	"log"
+	"sort"
 	"sync"
 )
 
@@ -17,25 +18,33 @@ type RingManager struct {
 	keyFetcher func(nodeRing, dataRing *Ring, id string) (nodes, error)
 	keyStorer  func(key string) error
 	keyRemover func(key string) error
+	// redirects holds the keys added while their owner was draining, see SetNodeState
+	redirects map[string]redirect
 }
 
-func NewRingManager() *RingManager {
+// redirect records that a key was placed on the node to instead of the draining node from
+type redirect struct {
+	from, to string
+}
+
+// NewRingManager creates a ring manager. The options configure the node ring; keys are placed with the same hasher.
+func NewRingManager(opts ...Option) *RingManager {
+	nr := NewRing(opts...)
 	dr := NewRing()
+	dr.Hasher, dr.hasher = nr.Hasher, nr.hasher
+	dr.stacked = true
 	return &RingManager{
-		nodeRing:   NewRing(),
+		nodeRing:   nr,
 		dataRing:   dr,
 		keyFetcher: defaultKeyFetcher,
 		keyStorer:  dr.defaultKeyStorer,
 		keyRemover: dr.defaultKeyRemover,
+		redirects:  make(map[string]redirect),
 	}
 }
 
 func (rm *RingManager) GetNodes() []string {
-	names := make([]string, len(rm.nodeRing.Nodes))
-	for i, n := range rm.nodeRing.Nodes {
-		names[i] = n.ID
-	}
-	return names
+	return rm.nodeRing.nodeIDs()
 }
 
 func (rm *RingManager) AddNode(nodeID string) error {
@@ -45,23 +54,107 @@ func (rm *RingManager) AddNode(nodeID string) error {
 	return rm.keyStorer(nodeID)
 }
 
+// AddWeightedNode adds a node that owns roughly weight times the keys of a node added with AddNode
+func (rm *RingManager) AddWeightedNode(nodeID string, weight float64) error {
+	rm.Lock()
+	defer rm.Unlock()
+	if err := rm.nodeRing.AddWeighted(nodeID, weight); err != nil {
+		return err
+	}
+	return rm.keyStorer(nodeID)
+}
+
 func (rm *RingManager) RemoveNode(nodeID string) error {
 	rm.Lock()
 	defer rm.Unlock()
 	rm.nodeRing.Remove(nodeID)
+	// keys redirected away from or to the node go back to where the ring places them
+	for key, rd := range rm.redirects {
+		if rd.from == nodeID || rd.to == nodeID {
+			delete(rm.redirects, key)
+		}
+	}
 	return rm.keyRemover(nodeID)
 }
 
+// SetNodeState changes the state of a node. A draining node keeps the keys it has, but keys added while it drains
+// go to the node that will own them once the draining node is removed. Those keys stay there even if the node is
+// set active again.
+func (rm *RingManager) SetNodeState(nodeID string, s NodeState) error {
+	rm.Lock()
+	defer rm.Unlock()
+	return rm.nodeRing.SetState(nodeID, s)
+}
+
 func (rm *RingManager) AddKey(key string) error {
-	return rm.keyStorer(key)
+	rm.Lock()
+	defer rm.Unlock()
+	existing := rm.dataRing.findNode(key) != -1
+	if err := rm.keyStorer(key); err != nil {
+		return err
+	}
+	if from, to := rm.placement(key); !existing && from != to {
+		rm.redirects[key] = redirect{from: from, to: to}
+	}
+	return nil
 }
 
 func (rm *RingManager) RemoveKey(key string) error {
+	rm.Lock()
+	defer rm.Unlock()
+	delete(rm.redirects, key)
 	return rm.keyRemover(key)
 }
 
 func (rm *RingManager) GetKeys(nodeID string) (nodes, error) {
-	return rm.keyFetcher(rm.nodeRing, rm.dataRing, nodeID)
+	rm.Lock()
+	defer rm.Unlock()
+	keys, err := rm.keyFetcher(rm.nodeRing, rm.dataRing, nodeID)
+	if err != nil || len(rm.redirects) == 0 {
+		return keys, err
+	}
+
+	owned := make(nodes, 0, len(keys))
+	for _, k := range keys {
+		if _, ok := rm.redirects[k.ID]; !ok {
+			owned = append(owned, k)
+		}
+	}
+	data := rm.dataRing.current()
+	for key, rd := range rm.redirects {
+		if rd.to != nodeID {
+			continue
+		}
+		if i := data.nodes.find(key, data.hasher(key)); i != -1 {
+			owned = append(owned, data.nodes[i])
+		}
+	}
+	sort.Sort(owned)
+	return owned, nil
+}
+
+// placement returns the node that owns the key in the node ring, and the node the key should be placed on, which is
+// the closest node counterclockwise from the owner that is not draining. Callers must hold the lock.
+func (rm *RingManager) placement(key string) (owner, target string) {
+	v := rm.nodeRing.current()
+	if len(v.nodes) == 0 {
+		return "", ""
+	}
+
+	// a node owns the keys between it and the next node, so the owner is the last node before the key
+	i := v.nodes.after(v.hasher(key)) - 1
+	if i < 0 {
+		i = len(v.nodes) - 1
+	}
+	owner, target = v.nodes[i].ID, v.nodes[i].ID
+	for j := 0; j < len(v.nodes) && v.states[target] == Draining; j++ {
+		i = (i - 1 + len(v.nodes)) % len(v.nodes)
+		target = v.nodes[i].ID
+	}
+	if v.states[target] == Draining {
+		return owner, owner // every node is draining
+	}
+	return owner, target
 }
 
 // SetKeyFetcher allows a user to override the default in memory ring store
@@ -87,68 +180,7 @@ func defaultKeyFetcher(nodeRing, dataRing *Ring, id string) (nodes, error) {
 	// r.Lock()
 	// defer r.Unlock()
 
-	startIndex := nodeRing.findNode(id)
-	if nodeRing.Nodes[startIndex].ID != id {
-		return nil, ErrNotFound
-	}
-	endIndex := startIndex + 1
-	wraps := false
-	if endIndex >= len(nodeRing.Nodes) {
-		endIndex = 0
-		wraps = true
-	}
-	debugf("looking for %q in", id)
-	for i := 0; i < len(nodeRing.Nodes); i++ {
-		debugf(">> node ring %+v", nodeRing.Nodes[i])
-	}
-
-	debugf("\nstartIndex (node %q): %d\nendIndex (the next node): %d", id, startIndex, endIndex)
-	debugf("node ring length: %d", len(nodeRing.Nodes))
-	debugf("data ring length: %d", len(dataRing.Nodes))
-
-	debugf("end := dataRing.searchByHashID(nodeRing.Nodes[endIndex].HashID)")
-	debugf("end := dataRing.searchByHashID(nodeRing.Nodes[%d].HashID)", endIndex)
-	debugf("end := dataRing.searchByHashID(%d)", nodeRing.Nodes[endIndex].HashID)
-	debugf("end := %d", dataRing.searchByHashID(nodeRing.Nodes[endIndex].HashID))
-
-	start := dataRing.searchByHashID(nodeRing.Nodes[startIndex].HashID)
-	end := dataRing.searchByHashID(nodeRing.Nodes[endIndex].HashID)
-
-	debugf("parsing dataNodes. [%d] -> [%d]", start, end)
-	for i := 0; i < len(dataRing.Nodes); i++ {
-		debugf(">> data ring [%d] %+v", i, dataRing.Nodes[i])
-
-	}
-
-	// we subtract 2 because we don't count the start and end keys themselves as they are the node hashes, not key hashes
-	size := start - end - 2
-	if size < 0 {
-		size = end - start - 2
-	}
-	if size < 0 {
-		size = 0
-	}
-	dataNodes := make(nodes, size)
-
-	if !wraps {
-		debugf("does not wrap")
-		for i := start + 1; i < end; i++ {
-			debugf("appending [%d] %+v", i, dataRing.Nodes[i])
-			dataNodes = append(dataNodes, dataRing.Nodes[i])
-		}
-	} else {
-		debugf("wraps")
-		for i := start + 1; i < len(dataRing.Nodes); i++ {
-			debugf("appending [%d] %+v", i, dataRing.Nodes[i])
-			dataNodes = append(dataNodes, dataRing.Nodes[i])
-		}
-		for i := 0; i < end; i++ {
-			debugf("appending [%d] %+v", i, dataRing.Nodes[i])
-			dataNodes = append(dataNodes, dataRing.Nodes[i])
-		}
-	}
-
-	return dataNodes, nil
+	return fetchKeys(nodeRing.current().nodes, dataRing.current().nodes, id)
 }
 
 func (r *Ring) defaultKeyStorer(key string) error {
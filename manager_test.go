@@ -1,6 +1,7 @@
 package chring_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/sethgrid/chring"
@@ -99,3 +100,61 @@ func TestManager(t *testing.T) {
 		t.Fatalf("got %d keys, want 0 keys in node a", len(keysInA))
 	}
 }
+
+func TestManagerWeightedNodes(t *testing.T) {
+	ringManager := chring.NewRingManager()
+	if err := ringManager.AddWeightedNode("node a", 3); err != nil {
+		t.Fatalf("got error %v, want nil adding a weighted node", err)
+	}
+	_ = ringManager.AddNode("node b")
+	for i := 1; i <= 100; i++ {
+		_ = ringManager.AddKey(fmt.Sprintf("user_%d", i))
+	}
+
+	keysInA, _ := ringManager.GetKeys("node a")
+	keysInB, _ := ringManager.GetKeys("node b")
+	if got := len(keysInA) + len(keysInB); got != 100 {
+		t.Errorf("got %d keys across both nodes, want 100", got)
+	}
+	for _, n := range append(keysInA, keysInB...) {
+		if n == nil || n.ID == "node a" || n.ID == "node b" {
+			t.Errorf("got %#v in the key list, want only keys", n)
+		}
+	}
+}
+
+func TestManagerDrainingNodes(t *testing.T) {
+	ringManager := chring.NewRingManager()
+	_ = ringManager.AddNode("node a")
+	_ = ringManager.AddNode("node b")
+	_ = ringManager.AddKey("user 180")
+
+	// see TestManager for the layout: user 180 and user 3 both belong to node a
+	if err := ringManager.SetNodeState("node a", chring.Draining); err != nil {
+		t.Fatal(err)
+	}
+	_ = ringManager.AddKey("user 3")
+
+	keysInA, _ := ringManager.GetKeys("node a")
+	if len(keysInA) != 1 || keysInA[0].ID != "user 180" {
+		t.Errorf("got %v, want the draining node to keep only user 180", keysInA)
+	}
+	keysInB, _ := ringManager.GetKeys("node b")
+	if len(keysInB) != 1 || keysInB[0].ID != "user 3" {
+		t.Errorf("got %v, want user 3 placed on node b", keysInB)
+	}
+
+	// setting the node active again leaves the new key where it was placed
+	_ = ringManager.SetNodeState("node a", chring.Active)
+	keysInB, _ = ringManager.GetKeys("node b")
+	if len(keysInB) != 1 {
+		t.Errorf("got %d keys, want 1 key in node b", len(keysInB))
+	}
+
+	// once node a is removed, its remaining key follows the new key
+	_ = ringManager.RemoveNode("node a")
+	keysInB, _ = ringManager.GetKeys("node b")
+	if len(keysInB) != 2 {
+		t.Errorf("got %d keys, want 2 keys in node b", len(keysInB))
+	}
+}
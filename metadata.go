@@ -0,0 +1,52 @@
+package chring
+
+// Member describes a node along with the details callers need to reach it, such as its address, zone or capacity
+type Member struct {
+	ID   string
+	Addr string
+	// Tags hold free-form details about the node, such as "zone" or "capacity". Tags returned from lookups are
+	// shared with the ring and must not be modified.
+	Tags map[string]string
+}
+
+// AddNode inserts a new node into the hash ring like Add, and keeps its address and tags for GetMember to return
+func (r *Ring) AddNode(m Member) {
+	tags := make(map[string]string, len(m.Tags))
+	for k, v := range m.Tags {
+		tags[k] = v
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.addMember(&member{ID: m.ID, Replicas: r.replicas, Weight: 1, Addr: m.Addr, Tags: tags})
+}
+
+// GetMember retrieves the closest node in the hash ring for the given key, along with its address and tags
+func (r *Ring) GetMember(key string) (Member, error) {
+	return r.current().GetMember(key)
+}
+
+// Member returns the address and tags of the given node
+func (r *Ring) Member(id string) (Member, error) {
+	v := r.current()
+	i := v.nodes.find(id, v.hasher(id))
+	if i == -1 {
+		return Member{}, ErrNotFound
+	}
+	return v.nodes[i].member.info(), nil
+}
+
+// GetMember retrieves the closest node in the view for the given key, along with its address and tags
+func (v *RingView) GetMember(key string) (Member, error) {
+	n := v.lookup(key)
+	if n == nil {
+		return Member{}, ErrNotFound
+	}
+	return n.member.info(), nil
+}
+
+// info describes the member to callers. Nodes added without AddNode have no address or tags.
+func (m *member) info() Member {
+	return Member{ID: m.ID, Addr: m.Addr, Tags: m.Tags}
+}
@@ -0,0 +1,49 @@
+package chring_test
+
+import (
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestAddNode(t *testing.T) {
+	ring := chring.NewRing(chring.WithReplicas(10))
+	tags := map[string]string{"zone": "us-east-1a"}
+	ring.AddNode(chring.Member{ID: "node 1", Addr: "10.0.0.1:6379", Tags: tags})
+	ring.AddNode(chring.Member{ID: "node 2", Addr: "10.0.0.2:6379", Tags: map[string]string{"zone": "us-east-1b"}})
+	ring.Add("node 3")
+	tags["zone"] = "changed after adding"
+
+	for _, key := range []string{"user A", "user B", "user C", "user D"} {
+		m, err := ring.GetMember(key)
+		if err != nil {
+			t.Fatalf("got error %v for GetMember(%q)", err, key)
+		}
+		if m.ID != ring.Get(key) {
+			t.Errorf("got member %q for %q, want %q", m.ID, key, ring.Get(key))
+		}
+	}
+
+	m, err := ring.Member("node 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Addr != "10.0.0.1:6379" || m.Tags["zone"] != "us-east-1a" {
+		t.Errorf("got %+v for node 1", m)
+	}
+
+	m, err = ring.Member("node 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "node 3" || m.Addr != "" || len(m.Tags) != 0 {
+		t.Errorf("got %+v for node 3, want no address or tags", m)
+	}
+
+	if _, err := ring.Member("node 4"); err != chring.ErrNotFound {
+		t.Errorf("got error %v for a missing node, want %v", err, chring.ErrNotFound)
+	}
+	if _, err := chring.NewRing().GetMember("user A"); err != chring.ErrNotFound {
+		t.Errorf("got error %v for an empty ring, want %v", err, chring.ErrNotFound)
+	}
+}
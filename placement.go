@@ -0,0 +1,68 @@
+package chring
+
+import "strings"
+
+// Placement tells GetNWithPlacement how to spread the replicas of a key across failure domains
+type Placement struct {
+	// SpreadBy lists the tags of the failure domains to spread replicas across, from the widest to the narrowest,
+	// for example "zone" then "rack". Nodes missing a tag share one empty domain for it.
+	SpreadBy []string
+}
+
+// SpreadBy returns a placement that spreads replicas across the given tags, from the widest domain to the narrowest
+func SpreadBy(tags ...string) Placement {
+	return Placement{SpreadBy: tags}
+}
+
+// GetNWithPlacement retrieves n distinct nodes for the given key, preferring nodes in failure domains that none of
+// the chosen nodes are in yet. See RingView.GetNWithPlacement.
+func (r *Ring) GetNWithPlacement(key string, n int, p Placement) ([]string, error) {
+	return r.current().GetNWithPlacement(key, n, p)
+}
+
+// GetNWithPlacement retrieves n distinct nodes for the given key, walking clockwise from the key as GetN does but
+// skipping nodes that share a failure domain with a node already chosen. With SpreadBy("zone", "rack"), replicas
+// first go to distinct zones; once every zone is used, to distinct racks within the zones; and once every rack is
+// used, to the remaining nodes in ring order. So having fewer zones or racks than replicas is not an error, and
+// the closest node is always the first one returned. Domains come from the tags given to AddNode.
+func (v *RingView) GetNWithPlacement(key string, n int, p Placement) ([]string, error) {
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	candidates, err := v.successors(key, v.available())
+	if err != nil {
+		return nil, err
+	}
+	if n > len(candidates) {
+		return nil, ErrNotEnoughNodes
+	}
+
+	found := make([]string, 0, n)
+	chosen := make([]bool, len(candidates))
+	used := make(map[string]bool)
+	for level := 0; level <= len(p.SpreadBy) && len(found) < n; level++ {
+		for i, nd := range candidates {
+			if len(found) == n {
+				break
+			}
+			if chosen[i] || (level < len(p.SpreadBy) && used[domain(nd.member, p.SpreadBy[:level+1])]) {
+				continue
+			}
+			chosen[i] = true
+			found = append(found, nd.ID)
+			for l := range p.SpreadBy {
+				used[domain(nd.member, p.SpreadBy[:l+1])] = true
+			}
+		}
+	}
+	return found, nil
+}
+
+// domain names the failure domain of a member for the given tags, e.g. "us-east-1a\x00rack 2" for zone and rack
+func domain(m *member, tags []string) string {
+	values := make([]string, len(tags))
+	for i, tag := range tags {
+		values[i] = m.Tags[tag]
+	}
+	return strings.Join(values, "\x00")
+}
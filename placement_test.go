@@ -0,0 +1,90 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+// newZonedRing places two racks of two nodes in each of three zones
+func newZonedRing() (*chring.Ring, map[string]chring.Member) {
+	ring := chring.NewRing(chring.WithReplicas(10))
+	members := map[string]chring.Member{}
+	for z := 0; z < 3; z++ {
+		for rack := 0; rack < 2; rack++ {
+			for i := 0; i < 2; i++ {
+				m := chring.Member{
+					ID:   fmt.Sprintf("node %d-%d-%d", z, rack, i),
+					Tags: map[string]string{"zone": fmt.Sprintf("zone %d", z), "rack": fmt.Sprintf("rack %d", rack)},
+				}
+				members[m.ID] = m
+				ring.AddNode(m)
+			}
+		}
+	}
+	return ring, members
+}
+
+func TestGetNWithPlacement(t *testing.T) {
+	ring, members := newZonedRing()
+
+	for k := 0; k < 100; k++ {
+		key := fmt.Sprintf("key %d", k)
+		found, err := ring.GetNWithPlacement(key, 6, chring.SpreadBy("zone", "rack"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found[0] != ring.Get(key) {
+			t.Errorf("got %q first for %q, want the closest node %q", found[0], key, ring.Get(key))
+		}
+
+		zones := map[string]bool{}
+		racks := map[string]bool{}
+		nodes := map[string]bool{}
+		for i, id := range found {
+			tags := members[id].Tags
+			zone, rack := tags["zone"], tags["zone"]+"/"+tags["rack"]
+			if i < 3 && zones[zone] {
+				t.Errorf("got %q in a zone already used by the first %d replicas of %q", id, i, key)
+			}
+			if racks[rack] {
+				t.Errorf("got %q in a rack already used for %q", id, key)
+			}
+			zones[zone], racks[rack], nodes[id] = true, true, true
+		}
+		if len(nodes) != 6 {
+			t.Errorf("got %d distinct nodes for %q, want 6", len(nodes), key)
+		}
+	}
+}
+
+func TestGetNWithPlacementFallsBack(t *testing.T) {
+	ring, _ := newZonedRing()
+
+	// more replicas than zones and racks
+	found, err := ring.GetNWithPlacement("user A", 12, chring.SpreadBy("zone", "rack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 12 {
+		t.Errorf("got %d nodes, want 12", len(found))
+	}
+
+	// a tag no node has puts every node in the same domain, which is just GetN
+	found, err = ring.GetNWithPlacement("user A", 4, chring.SpreadBy("region"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := ring.GetN("user A", 4)
+	if fmt.Sprint(found) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", found, want)
+	}
+
+	if _, err := ring.GetNWithPlacement("user A", 13, chring.SpreadBy("zone")); err != chring.ErrNotEnoughNodes {
+		t.Errorf("got error %v, want %v", err, chring.ErrNotEnoughNodes)
+	}
+	if _, err := ring.GetNWithPlacement("user A", -1, chring.SpreadBy("zone")); err != chring.ErrNegativeCount {
+		t.Errorf("got error %v, want %v", err, chring.ErrNegativeCount)
+	}
+}
@@ -0,0 +1,188 @@
+package chring
+
+import (
+	"sort"
+	"strconv"
+)
+
+// position is the width of a ring's hash space
+type position interface {
+	~uint32 | ~uint64
+}
+
+// point is a virtual node placed in a hash ring of any width
+type point[H position] struct {
+	ID     string
+	HashID H
+	member *member // set by rings that track metadata for their nodes
+}
+
+// newNode creates a new node to go into the hash ring
+func newNode[H position](id string, fn func(id string) H) *point[H] {
+	return &point[H]{
+		ID:     id,
+		HashID: fn(id),
+	}
+}
+
+// newVirtualNode creates the i-th virtual node for the given ID. The first replica sits at the plain hash of the ID
+// so that a single replica ring places nodes exactly where newNode would.
+func newVirtualNode[H position](id string, i int, fn func(id string) H) *point[H] {
+	if i == 0 {
+		return newNode(id, fn)
+	}
+	return &point[H]{
+		ID:     id,
+		HashID: fn(id + "#" + strconv.Itoa(i)),
+	}
+}
+
+// points is a ring's virtual nodes, kept sorted by hash
+type points[H position] []*point[H]
+
+// Len() is for matching the swap interface
+func (p points[H]) Len() int { return len(p) }
+
+// Swap() is for matching the swap interface
+func (p points[H]) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+// Less() is for matching the swap interface. Points sharing a position are ordered by ID so that the order does not
+// depend on the order they were added in.
+func (p points[H]) Less(i, j int) bool {
+	return p[i].HashID < p[j].HashID || p[i].HashID == p[j].HashID && p[i].ID < p[j].ID
+}
+
+// after returns the index of the first point past the given hash, or len(p) when the hash is past the last point
+func (p points[H]) after(hashID H) int {
+	return sort.Search(len(p), func(i int) bool {
+		return p[i].HashID > hashID
+	})
+}
+
+// searchByHashID finds the point closest to the given hashID
+func (p points[H]) searchByHashID(hashID H) int {
+	nodeAfter := p.after(hashID)
+	if nodeAfter <= 0 {
+		return nodeAfter
+	}
+	return nodeAfter - 1
+}
+
+// find returns the index of the point for id at hashID, falling back to any point for id, or -1 if there is none
+func (p points[H]) find(id string, hashID H) int {
+	i := sort.Search(len(p), func(i int) bool {
+		return p[i].HashID >= hashID
+	})
+	for ; i < len(p) && p[i].HashID == hashID; i++ {
+		if p[i].ID == id {
+			return i
+		}
+	}
+	for i, n := range p {
+		if n.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// occupied reports whether any point sits at the given hash position
+func (p points[H]) occupied(hashID H) bool {
+	i := sort.Search(len(p), func(i int) bool {
+		return p[i].HashID >= hashID
+	})
+	return i < len(p) && p[i].HashID == hashID
+}
+
+// indexesOf returns the index of every point belonging to the given ID, in ring order
+func (p points[H]) indexesOf(id string) []int {
+	var found []int
+	for i, n := range p {
+		if n.ID == id {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// ids returns each distinct ID once, in the order they first appear in the ring
+func (p points[H]) ids() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, n := range p {
+		if !seen[n.ID] {
+			seen[n.ID] = true
+			ids = append(ids, n.ID)
+		}
+	}
+	return ids
+}
+
+// with returns a sorted copy of the points with the given points added
+func (p points[H]) with(added ...*point[H]) points[H] {
+	ns := make(points[H], 0, len(p)+len(added))
+	ns = append(append(ns, p...), added...)
+	sort.Sort(ns)
+	return ns
+}
+
+// without returns a copy of the points that do not belong to the given ID
+func (p points[H]) without(id string) points[H] {
+	kept := make(points[H], 0, len(p))
+	for _, n := range p {
+		if n.ID != id {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// walk visits the points clockwise starting at index start, wrapping around the ring at most once, until fn returns false
+func (p points[H]) walk(start int, fn func(n *point[H]) bool) {
+	for i := 0; i < len(p); i++ {
+		if !fn(p[(start+i)%len(p)]) {
+			return
+		}
+	}
+}
+
+// between returns the points whose hashes fall strictly between start and end, walking clockwise and wrapping
+// past the end of the ring when needed. The bounds themselves are excluded as they are node hashes, not key hashes.
+func (p points[H]) between(start, end H) points[H] {
+	var found points[H]
+	p.walk(p.after(start), func(n *point[H]) bool {
+		if start < end && (n.HashID <= start || n.HashID >= end) {
+			return false
+		}
+		if start >= end && n.HashID >= end && n.HashID <= start {
+			return false
+		}
+		debugf("appending %+v", n)
+		found = append(found, n)
+		return true
+	})
+	return found
+}
+
+// fetchKeys returns the keys in dataRing owned by the node id in nodeRing. A node owns the keys between each of its
+// virtual nodes and the next virtual node in the ring.
+func fetchKeys[H position](nodeRing, dataRing points[H], id string) (points[H], error) {
+	indexes := nodeRing.indexesOf(id)
+	if len(indexes) == 0 {
+		return nil, ErrNotFound
+	}
+	debugf("looking for %q in", id)
+	for i := 0; i < len(nodeRing); i++ {
+		debugf(">> node ring %+v", nodeRing[i])
+	}
+	debugf("node ring length: %d", len(nodeRing))
+	debugf("data ring length: %d", len(dataRing))
+
+	var dataNodes points[H]
+	for _, startIndex := range indexes {
+		endIndex := (startIndex + 1) % len(nodeRing)
+		debugf("startIndex (node %q): %d, endIndex (the next node): %d", id, startIndex, endIndex)
+		dataNodes = append(dataNodes, dataRing.between(nodeRing[startIndex].HashID, nodeRing[endIndex].HashID)...)
+	}
+	return dataNodes, nil
+}
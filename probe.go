@@ -0,0 +1,46 @@
+package chring
+
+import (
+	"strconv"
+)
+
+// WithProbes turns on multi-probe consistent hashing (Appleton and O'Reilly). Each key is hashed k times and routed
+// to the node closest clockwise to any of its probes, which balances keys about as well as k virtual nodes per node
+// would, with nodes sitting at a single point each. Lookups cost k searches instead of one. The paper reports a
+// peak to mean load ratio of 1.05 with 21 probes.
+func WithProbes(k int) Option {
+	return func(c *config) {
+		if k > 0 {
+			c.probes = k
+		}
+	}
+}
+
+// locate returns the index of the node in the view that the key routes to, which may be len(v.nodes) when the ring
+// wraps around. With a single probe it is the same as search.
+func (v *RingView) locate(key string) int {
+	if v.probes <= 1 || len(v.nodes) == 0 {
+		return v.search(key)
+	}
+
+	best := -1
+	var bestDistance uint32
+	for i := 0; i < v.probes; i++ {
+		hashID := v.hasher(probeKey(key, i))
+		next := v.nodes.after(hashID) % len(v.nodes)
+		// unsigned subtraction measures the clockwise distance, wrapping past the end of the ring
+		distance := v.nodes[next].HashID - hashID
+		if best == -1 || distance < bestDistance {
+			best, bestDistance = next, distance
+		}
+	}
+	return best
+}
+
+// probeKey is the value hashed for the i-th probe of a key. The first probe is the key itself.
+func probeKey(key string, i int) string {
+	if i == 0 {
+		return key
+	}
+	return key + "\x00" + strconv.Itoa(i)
+}
@@ -0,0 +1,67 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+// peakToMean routes keys through the ring and returns the most loaded node's key count over the average
+func peakToMean(ring *chring.Ring, keys int) float64 {
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		counts[ring.Get(fmt.Sprintf("user_%d", i))]++
+	}
+	peak := 0
+	for _, count := range counts {
+		if count > peak {
+			peak = count
+		}
+	}
+	return float64(peak) / (float64(keys) / float64(len(ring.Nodes())))
+}
+
+func TestMultiProbeDistribution(t *testing.T) {
+	single := chring.NewRing(chring.WithHasher(chring.XXHash))
+	probed := chring.NewRing(chring.WithHasher(chring.XXHash), chring.WithProbes(21))
+	for i := 0; i < 20; i++ {
+		single.Add(fmt.Sprintf("10.0.0.%d", i))
+		probed.Add(fmt.Sprintf("10.0.0.%d", i))
+	}
+
+	singleRatio := peakToMean(single, 100000)
+	probedRatio := peakToMean(probed, 100000)
+	t.Logf("peak to mean ratio over 20 nodes: 1 probe %.3f, 21 probes %.3f", singleRatio, probedRatio)
+	if probedRatio > 1.25 {
+		t.Errorf("got peak to mean ratio %.3f with 21 probes, want at most 1.25", probedRatio)
+	}
+	if probedRatio >= singleRatio {
+		t.Errorf("got peak to mean ratio %.3f with 21 probes, want better than %.3f with a single probe", probedRatio, singleRatio)
+	}
+}
+
+func TestMultiProbeIsConsistent(t *testing.T) {
+	ring := chring.NewRing(chring.WithProbes(5))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+
+	want := ring.Get("foo")
+	for i := 0; i < 100; i++ {
+		if got := ring.Get("foo"); got != want {
+			t.Fatalf("got %q, want %q for every lookup", got, want)
+		}
+	}
+	replicas, err := ring.GetN("foo", 2)
+	if err != nil || replicas[0] != want {
+		t.Errorf("got %v (error %v), want the first replica to be %q", replicas, err, want)
+	}
+
+	_ = ring.Remove("node 2")
+	for i := 0; i < 1000; i++ {
+		if got := ring.Get(fmt.Sprintf("user_%d", i)); got == "node 2" {
+			t.Fatalf("got %q for a key after it was removed", got)
+		}
+	}
+}
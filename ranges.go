@@ -0,0 +1,110 @@
+package chring
+
+import (
+	"math"
+	"sort"
+)
+
+// HashRange is an inclusive range of positions in a ring's hash space
+type HashRange struct {
+	Start, End uint32
+}
+
+// Contains reports whether the hash position falls within the range
+func (h HashRange) Contains(hashID uint32) bool {
+	return h.Start <= hashID && hashID <= h.End
+}
+
+// OwnerOf returns the node owning the hash position, which is the node Get returns for a key with that hash
+// (ignoring probes), or "" if the ring is empty or every node is down
+func (r *Ring) OwnerOf(hashID uint32) string {
+	return r.current().OwnerOf(hashID)
+}
+
+// Ranges returns the ranges of the hash space owned by the node, sorted by position. Each virtual node owns the
+// positions from the previous virtual node up to its own, so the first virtual node in the ring also owns the range
+// that wraps around the end of the hash space; that range is returned in two parts. The positions of a down node's
+// virtual nodes are owned by the next node clockwise that is not down, as Get routes them, so a down node owns no
+// ranges. Ranges of consecutive virtual nodes are merged.
+func (r *Ring) Ranges(id string) []HashRange {
+	return r.current().Ranges(id)
+}
+
+// OwnerOf returns the node in the view owning the hash position, or "" for an empty or nil view
+func (v *RingView) OwnerOf(hashID uint32) string {
+	if v == nil || len(v.nodes) == 0 {
+		return ""
+	}
+	// searchByHashID finds the virtual node at or before the position, which owns up to its own position only
+	i := v.nodes.searchByHashID(hashID)
+	if v.nodes[i].HashID <= hashID {
+		i = (i + 1) % len(v.nodes)
+	}
+	if n := v.owner(i); n != nil {
+		return n.ID
+	}
+	return ""
+}
+
+// owner returns the virtual node serving the positions up to the virtual node at index i: the first virtual node
+// from i clockwise whose node is not down, or nil if every node is down
+func (v *RingView) owner(i int) *node {
+	var found *node
+	v.nodes.walk(i, func(n *node) bool {
+		if v.states[n.ID] == Down {
+			return true
+		}
+		found = n
+		return false
+	})
+	return found
+}
+
+// Ranges returns the ranges of the hash space owned by the node in the view, see Ring.Ranges
+func (v *RingView) Ranges(id string) []HashRange {
+	if len(v.nodes) == 0 {
+		return nil
+	}
+	if v.states[id] == Down {
+		return nil
+	}
+	if v.size == 1 && v.nodes[0].ID == id {
+		return []HashRange{{Start: 0, End: math.MaxUint32}}
+	}
+
+	var ranges []HashRange
+	for i, n := range v.nodes {
+		if o := v.owner(i); o == nil || o.ID != id {
+			continue
+		}
+		if i == 0 {
+			// the wrap around range from the last virtual node past the end of the hash space
+			ranges = append(ranges, HashRange{Start: v.nodes[len(v.nodes)-1].HashID, End: math.MaxUint32})
+			if n.HashID > 0 {
+				ranges = append(ranges, HashRange{Start: 0, End: n.HashID - 1})
+			}
+			continue
+		}
+		if prev := v.nodes[i-1]; prev.HashID < n.HashID {
+			ranges = append(ranges, HashRange{Start: prev.HashID, End: n.HashID - 1})
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// mergeRanges sorts ranges by position and joins the ones that touch
+func mergeRanges(ranges []HashRange) []HashRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+
+	var merged []HashRange
+	for _, h := range ranges {
+		if last := len(merged) - 1; last >= 0 && merged[last].End != math.MaxUint32 && merged[last].End+1 >= h.Start {
+			if h.End > merged[last].End {
+				merged[last].End = h.End
+			}
+			continue
+		}
+		merged = append(merged, h)
+	}
+	return merged
+}
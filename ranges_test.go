@@ -0,0 +1,73 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestRangesCoverTheRing(t *testing.T) {
+	ring := chring.NewRing(chring.WithReplicas(30))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+
+	var total uint64
+	for _, n := range NodeList {
+		ranges := ring.Ranges(n)
+		if len(ranges) == 0 {
+			t.Errorf("got no ranges for %q", n)
+		}
+		for _, h := range ranges {
+			total += uint64(h.End-h.Start) + 1
+			if got := ring.OwnerOf(h.Start); got != n {
+				t.Errorf("got owner %q for the start of %+v, want %q", got, h, n)
+			}
+			if got := ring.OwnerOf(h.End); got != n {
+				t.Errorf("got owner %q for the end of %+v, want %q", got, h, n)
+			}
+		}
+	}
+	if total != 1<<32 {
+		t.Errorf("got ranges covering %d positions, want the whole hash space of %d", total, uint64(1<<32))
+	}
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user_%d", i)
+		if got, want := ring.OwnerOf(chring.DefaultHasher(key)), ring.Get(key); got != want {
+			t.Errorf("got owner %q for the hash of %q, want %q to match Get", got, key, want)
+		}
+	}
+}
+
+func TestRangesWrapAround(t *testing.T) {
+	ring := chring.NewRing()
+	if got := ring.OwnerOf(5); got != "" {
+		t.Errorf("got owner %q in an empty ring, want none", got)
+	}
+
+	_ = ring.AddAt("a", 100, 300)
+	if got := ring.Ranges("a"); len(got) != 1 || got[0] != (chring.HashRange{Start: 0, End: 4294967295}) {
+		t.Errorf("got %+v, want the whole hash space for the only node", got)
+	}
+
+	_ = ring.AddAt("b", 200)
+	wantA := []chring.HashRange{{Start: 0, End: 99}, {Start: 200, End: 4294967295}}
+	if got := ring.Ranges("a"); fmt.Sprint(got) != fmt.Sprint(wantA) {
+		t.Errorf("got %+v, want %+v", got, wantA)
+	}
+	wantB := []chring.HashRange{{Start: 100, End: 199}}
+	if got := ring.Ranges("b"); fmt.Sprint(got) != fmt.Sprint(wantB) {
+		t.Errorf("got %+v, want %+v", got, wantB)
+	}
+	if got := ring.Ranges("c"); len(got) != 0 {
+		t.Errorf("got %+v, want no ranges for an unknown node", got)
+	}
+
+	for hashID, want := range map[uint32]string{0: "a", 99: "a", 100: "b", 199: "b", 200: "a", 300: "a", 4294967295: "a"} {
+		if got := ring.OwnerOf(hashID); got != want {
+			t.Errorf("got owner %q for #%d, want %q", got, hashID, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package chring
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// RendezvousRing routes keys with rendezvous (highest random weight) hashing. Every node scores every key and the
+// highest score wins, so removing a node only moves the keys it owned and weights need no virtual nodes. Lookups
+// cost O(n) in the number of nodes, so prefer Ring for large clusters.
+type RendezvousRing struct {
+	sync.Mutex
+	nodes   []*member
+	members map[string]*member
+	hasher  func(id string) uint64
+}
+
+// NewRendezvousRing creates a new rendezvous ring hashing with xxHash. Only WithHasher is honored; a hasher without
+// a 64 bit form is widened from its 32 bit hash.
+func NewRendezvousRing(opts ...Option) *RendezvousRing {
+	c := newConfig(opts)
+	return &RendezvousRing{members: make(map[string]*member), hasher: hash64(c.hasher)}
+}
+
+// Add inserts a new node with a weight of 1
+func (r *RendezvousRing) Add(id string) {
+	_ = r.AddWeighted(id, 1)
+}
+
+// AddWeighted inserts a new node that wins roughly weight times the keys of a node added with Add
+func (r *RendezvousRing) AddWeighted(id string, weight float64) error {
+	if !validWeight(weight) {
+		return ErrInvalidWeight
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	// don't insert the same node more than once
+	if _, ok := r.members[id]; ok {
+		return nil
+	}
+	m := &member{ID: id, Weight: weight}
+	r.members[id] = m
+	r.nodes = append(r.nodes, m)
+	return nil
+}
+
+// Remove takes the node out of the ring. Only the keys it owned move to other nodes.
+func (r *RendezvousRing) Remove(id string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.members[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.members, id)
+	for i, m := range r.nodes {
+		if m.ID == id {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Get retrieves the node with the highest score for the given key
+func (r *RendezvousRing) Get(key string) string {
+	r.Lock()
+	defer r.Unlock()
+
+	var best string
+	bestScore := math.Inf(-1)
+	for _, m := range r.nodes {
+		score := r.score(m, key)
+		if score > bestScore || (score == bestScore && m.ID < best) {
+			best, bestScore = m.ID, score
+		}
+	}
+	return best
+}
+
+// GetN retrieves the n nodes with the highest scores for the given key, best first
+func (r *RendezvousRing) GetN(key string, n int) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	if n > len(r.nodes) {
+		return nil, ErrNotEnoughNodes
+	}
+
+	type scored struct {
+		id    string
+		score float64
+	}
+	all := make([]scored, len(r.nodes))
+	for i, m := range r.nodes {
+		all[i] = scored{m.ID, r.score(m, key)}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].score == all[j].score {
+			return all[i].id < all[j].id
+		}
+		return all[i].score > all[j].score
+	})
+
+	found := make([]string, n)
+	for i := range found {
+		found[i] = all[i].id
+	}
+	return found, nil
+}
+
+// Nodes returns the ID of every node in the order they were added
+func (r *RendezvousRing) Nodes() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	ids := make([]string, len(r.nodes))
+	for i, m := range r.nodes {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// score is the weighted rendezvous score of a node for a key, -weight / ln(u) where u is the hash of the pair
+// mapped into the open interval (0, 1). It makes each node win in proportion to its weight. The hash is cut to 52
+// bits so that u, half a step past it, is exact and never rounds up to 1, where ln(u) is 0 and the score -Inf.
+func (r *RendezvousRing) score(m *member, key string) float64 {
+	h := r.hasher(m.ID + "\x00" + key)
+	u := (float64(h>>12) + 0.5) / (1 << 52)
+	return -m.Weight / math.Log(u)
+}
@@ -0,0 +1,116 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestRendezvousRing(t *testing.T) {
+	ring := chring.NewRendezvousRing()
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[ring.Get(fmt.Sprintf("user_%d", i))]++
+	}
+	for _, n := range NodeList {
+		if counts[n] < 2200 || counts[n] > 2800 {
+			t.Errorf("got %d of 10000 keys on %q, want about 2500", counts[n], n)
+		}
+	}
+
+	replicas, err := ring.GetN("user A", 3)
+	if err != nil || len(replicas) != 3 || replicas[0] != ring.Get("user A") {
+		t.Errorf("got %v (error %v), want 3 nodes starting with %q", replicas, err, ring.Get("user A"))
+	}
+	if _, err := ring.GetN("user A", len(NodeList)+1); err != chring.ErrNotEnoughNodes {
+		t.Errorf("got error %v, want %v", err, chring.ErrNotEnoughNodes)
+	}
+	if _, err := ring.GetN("user A", -1); err != chring.ErrNegativeCount {
+		t.Errorf("got error %v, want %v", err, chring.ErrNegativeCount)
+	}
+}
+
+func TestRendezvousWeights(t *testing.T) {
+	ring := chring.NewRendezvousRing(chring.WithHasher(chring.Murmur3))
+	_ = ring.AddWeighted("big", 3)
+	_ = ring.AddWeighted("small", 1)
+
+	big := 0
+	for i := 0; i < 10000; i++ {
+		if ring.Get(fmt.Sprintf("user_%d", i)) == "big" {
+			big++
+		}
+	}
+	if big < 7000 || big > 8000 {
+		t.Errorf("got %d of 10000 keys on the big node, want about 7500", big)
+	}
+	if err := ring.AddWeighted("none", -1); err != chring.ErrInvalidWeight {
+		t.Errorf("got error %v, want %v", err, chring.ErrInvalidWeight)
+	}
+}
+
+// TestRendezvousMovementOnRemoval compares how many keys move when a node is removed. Both algorithms only move the
+// keys of the removed node, but rendezvous hashing gives every node close to an equal share without virtual nodes,
+// so the number of keys moved stays close to 1/n.
+func TestRendezvousMovementOnRemoval(t *testing.T) {
+	var nodeList []string
+	for i := 0; i < 10; i++ {
+		nodeList = append(nodeList, fmt.Sprintf("10.0.0.%d", i))
+	}
+	routers := map[string]chring.Router{
+		"ring":       chring.NewRing(),
+		"rendezvous": chring.NewRendezvousRing(),
+	}
+
+	moved := make(map[string]int)
+	for name, router := range routers {
+		for _, n := range nodeList {
+			router.Add(n)
+		}
+		before := make(map[string]string)
+		for i := 0; i < 10000; i++ {
+			key := fmt.Sprintf("user_%d", i)
+			before[key] = router.Get(key)
+		}
+
+		removed := nodeList[3]
+		_ = router.Remove(removed)
+		for key, was := range before {
+			now := router.Get(key)
+			if now == was {
+				continue
+			}
+			moved[name]++
+			if was != removed {
+				t.Errorf("%s: got %q moving from %q to %q, want only keys of the removed node to move", name, key, was, now)
+			}
+		}
+	}
+
+	t.Logf("keys moved removing 1 of 10 nodes: ring %d, rendezvous %d", moved["ring"], moved["rendezvous"])
+	if moved["rendezvous"] < 700 || moved["rendezvous"] > 1300 {
+		t.Errorf("got %d keys moved, want about 1000", moved["rendezvous"])
+	}
+}
+
+// extremeHasher hashes every ID to the same value
+type extremeHasher uint64
+
+func (h extremeHasher) Name() string         { return "extreme" }
+func (h extremeHasher) Hash32(string) uint32 { return uint32(h) }
+func (h extremeHasher) Hash64(string) uint64 { return uint64(h) }
+
+func TestRendezvousExtremeHashes(t *testing.T) {
+	for _, h := range []extremeHasher{0, ^extremeHasher(0)} {
+		ring := chring.NewRendezvousRing(chring.WithHasher(h))
+		ring.Add("a")
+		if got := ring.Get("user A"); got != "a" {
+			t.Errorf("got %q for a hash of %#x, want the only node %q", got, uint64(h), "a")
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package chring
+
+import (
+	"sort"
+	"sync"
+)
+
+// Ring64 is a consistent hash ring over a 64 bit hash space. It works like Ring, but places nodes and keys with
+// uint64 hashes, so that collisions stay unlikely and ranges stay fine grained with many virtual nodes and millions
+// of keys. Use NewRing64() to create a ring.
+type Ring64 struct {
+	sync.Mutex
+	// nodes is replaced rather than modified by changes, so a slice read under the lock stays valid, see current
+	nodes    nodes64
+	Hasher   func(id string) uint64
+	replicas int
+	members  map[string]*member
+}
+
+// NewRing64 creates a new 64 bit consistent hash ring hashing with xxHash. WithReplicas and WithHasher are honored;
+// a hasher without a 64 bit form is widened from its 32 bit hash, which works but gives up the wider hash space.
+func NewRing64(opts ...Option) *Ring64 {
+	c := newConfig(opts)
+	return &Ring64{nodes: nodes64{}, Hasher: hash64(c.hasher), replicas: c.replicas, members: make(map[string]*member)}
+}
+
+// Add inserts a new node into the hash ring using the ring's configured replica count
+func (r *Ring64) Add(id string) {
+	r.AddWithReplicas(id, r.replicas)
+}
+
+// AddWithReplicas inserts a new node into the hash ring at n virtual positions
+func (r *Ring64) AddWithReplicas(id string, n int) {
+	r.Lock()
+	defer r.Unlock()
+
+	if n < 1 {
+		n = 1
+	}
+	r.addMember(&member{ID: id, Replicas: n, Weight: float64(n) / float64(r.replicas)})
+}
+
+// AddWeighted inserts a new node into the hash ring owning roughly weight times the hash space of a node added with Add
+func (r *Ring64) AddWeighted(id string, weight float64) error {
+	if !validWeight(weight) {
+		return ErrInvalidWeight
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.addMember(&member{ID: id, Replicas: weightedReplicas(r.replicas, weight), Weight: weight})
+	return nil
+}
+
+// addMember records the member and places its virtual nodes, unless a node with the same ID is already in the ring.
+// Callers must hold the lock.
+func (r *Ring64) addMember(m *member) {
+	if _, ok := r.members[m.ID]; ok {
+		return
+	}
+	r.members[m.ID] = m
+	ns := make(nodes64, len(r.nodes), len(r.nodes)+m.Replicas)
+	copy(ns, r.nodes)
+	for i := 0; i < m.Replicas; i++ {
+		ns = append(ns, newVirtualNode(m.ID, i, r.Hasher))
+	}
+	sort.Sort(ns)
+	r.nodes = ns
+}
+
+// Remove takes the node and all of its virtual nodes out of the hash ring
+func (r *Ring64) Remove(id string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.members[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.members, id)
+	r.nodes = r.nodes.without(id)
+	return nil
+}
+
+// Get retrieves the closest node in the hash ring for the given key
+func (r *Ring64) Get(key string) string {
+	r.Lock()
+	defer r.Unlock()
+
+	if len(r.nodes) == 0 {
+		return ""
+	}
+
+	i := r.search(key)
+	if i >= r.nodes.Len() {
+		i = 0 // wrap around to the initial node
+	}
+	return r.nodes[i].ID
+}
+
+// Nodes returns the ID of every node in the ring once, in ring order
+func (r *Ring64) Nodes() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.nodeIDs()
+}
+
+// GetN retrieves the n distinct nodes that follow the given key clockwise in the hash ring, closest first
+func (r *Ring64) GetN(key string, n int) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	if n > len(r.members) {
+		return nil, ErrNotEnoughNodes
+	}
+
+	found := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	r.nodes.walk(r.search(key), func(nd *node64) bool {
+		if len(found) == n {
+			return false
+		}
+		if !seen[nd.ID] {
+			seen[nd.ID] = true
+			found = append(found, nd.ID)
+		}
+		return true
+	})
+	return found, nil
+}
+
+// current returns the ring's virtual nodes, which may be read without the lock as changes replace the slice
+func (r *Ring64) current() nodes64 {
+	r.Lock()
+	defer r.Unlock()
+	return r.nodes
+}
+
+// findNode returns the index of the node's primary position, or -1 if the node is not in the ring
+func (r *Ring64) findNode(id string) int {
+	return r.nodes.find(id, r.Hasher(id))
+}
+
+// nodeIDs returns each distinct node ID once, in the order they first appear in the ring
+func (r *Ring64) nodeIDs() []string {
+	return r.nodes.ids()
+}
+
+// search finds the next node in the hash ring for a given key
+func (r *Ring64) search(key string) int {
+	return r.nodes.after(r.Hasher(key))
+}
+
+// searchByHashID finds the node closest to the given hashID
+func (r *Ring64) searchByHashID(hashID uint64) int {
+	return r.nodes.searchByHashID(hashID)
+}
+
+// node64 is a virtual node in a 64 bit hash ring
+type node64 = point[uint64]
+
+// nodes64 is a 64 bit ring's virtual nodes, sorted by hash
+type nodes64 = points[uint64]
+
+// RingManager64 is a RingManager over 64 bit hash rings
+type RingManager64 struct {
+	sync.Mutex
+	nodeRing   *Ring64
+	dataRing   *Ring64
+	keyFetcher func(nodeRing, dataRing *Ring64, id string) (nodes64, error)
+	keyStorer  func(key string) error
+	keyRemover func(key string) error
+}
+
+// NewRingManager64 creates a 64 bit ring manager. The options configure the node ring; keys are placed with the same hasher.
+func NewRingManager64(opts ...Option) *RingManager64 {
+	nr := NewRing64(opts...)
+	dr := NewRing64()
+	dr.Hasher = nr.Hasher
+	return &RingManager64{
+		nodeRing:   nr,
+		dataRing:   dr,
+		keyFetcher: defaultKeyFetcher64,
+		keyStorer:  dr.defaultKeyStorer,
+		keyRemover: dr.defaultKeyRemover,
+	}
+}
+
+func (rm *RingManager64) GetNodes() []string {
+	return rm.nodeRing.current().ids()
+}
+
+func (rm *RingManager64) AddNode(nodeID string) error {
+	rm.Lock()
+	defer rm.Unlock()
+	rm.nodeRing.Add(nodeID)
+	return rm.keyStorer(nodeID)
+}
+
+func (rm *RingManager64) RemoveNode(nodeID string) error {
+	rm.Lock()
+	defer rm.Unlock()
+	rm.nodeRing.Remove(nodeID)
+	return rm.keyRemover(nodeID)
+}
+
+func (rm *RingManager64) AddKey(key string) error {
+	return rm.keyStorer(key)
+}
+
+func (rm *RingManager64) RemoveKey(key string) error {
+	return rm.keyRemover(key)
+}
+
+func (rm *RingManager64) GetKeys(nodeID string) (nodes64, error) {
+	return rm.keyFetcher(rm.nodeRing, rm.dataRing, nodeID)
+}
+
+// SetKeyFetcher allows a user to override the default in memory ring store
+func (rm *RingManager64) SetKeyFetcher(fn func(nodeRing, dataRing *Ring64, id string) (nodes64, error)) {
+	rm.keyFetcher = fn
+}
+
+// SetKeyStorer allows a user to override the default in memory key store
+func (rm *RingManager64) SetKeyStorer(fn func(key string) error) {
+	rm.keyStorer = fn
+}
+
+func defaultKeyFetcher64(nodeRing, dataRing *Ring64, id string) (nodes64, error) {
+	return fetchKeys(nodeRing.current(), dataRing.current(), id)
+}
+
+func (r *Ring64) defaultKeyStorer(key string) error {
+	r.Add(key)
+	return nil
+}
+
+func (r *Ring64) defaultKeyRemover(key string) error {
+	r.Remove(key)
+	return nil
+}
@@ -0,0 +1,84 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestRing64(t *testing.T) {
+	ring := chring.NewRing64(chring.WithReplicas(100))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	if got, want := len(ring.Nodes()), len(NodeList); got != want {
+		t.Fatalf("got %d nodes, want %d", got, want)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[ring.Get(fmt.Sprintf("user_%d", i))]++
+	}
+	for _, n := range NodeList {
+		if counts[n] < 1500 {
+			t.Errorf("got %d of 10000 keys on %q, want a more even distribution", counts[n], n)
+		}
+	}
+
+	replicas, err := ring.GetN("user A", 3)
+	if err != nil || len(replicas) != 3 || replicas[0] != ring.Get("user A") {
+		t.Errorf("got %v (error %v), want 3 nodes starting with %q", replicas, err, ring.Get("user A"))
+	}
+	if _, err := ring.GetN("user A", -1); err != chring.ErrNegativeCount {
+		t.Errorf("got error %v, want %v for a negative count", err, chring.ErrNegativeCount)
+	}
+
+	if err := ring.Remove("node 1"); err != nil {
+		t.Fatalf("got error %v, want nil when removing a known node", err)
+	}
+	if err := ring.Remove("node 1"); err != chring.ErrNotFound {
+		t.Errorf("got error %v, want %v when removing a node twice", err, chring.ErrNotFound)
+	}
+	for i := 0; i < 1000; i++ {
+		if got := ring.Get(fmt.Sprintf("user_%d", i)); got == "node 1" {
+			t.Fatalf("got %q for a key after it was removed", got)
+		}
+	}
+}
+
+func TestRing64Widens32BitHashers(t *testing.T) {
+	ring := chring.NewRing64(chring.WithHasher(chring.CRC32), chring.WithReplicas(20))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	for _, key := range []string{"user A", "user B", "user C", "user D"} {
+		if got := ring.Get(key); !In(got, NodeList) {
+			t.Errorf("got %q for Get(%q)", got, key)
+		}
+	}
+}
+
+func TestRingManager64(t *testing.T) {
+	ringManager := chring.NewRingManager64(chring.WithHasher(chring.Murmur3))
+	_ = ringManager.AddNode("node a")
+	_ = ringManager.AddNode("node b")
+	for i := 1; i <= 100; i++ {
+		_ = ringManager.AddKey(fmt.Sprintf("user_%d", i))
+	}
+
+	keysInA, _ := ringManager.GetKeys("node a")
+	keysInB, _ := ringManager.GetKeys("node b")
+	if got := len(keysInA) + len(keysInB); got != 100 {
+		t.Errorf("got %d keys across both nodes, want 100", got)
+	}
+
+	_ = ringManager.RemoveNode("node b")
+	keysInA, _ = ringManager.GetKeys("node a")
+	if got := len(keysInA); got != 100 {
+		t.Errorf("got %d keys in node a, want all 100 once node b is removed", got)
+	}
+	if _, err := ringManager.GetKeys("node b"); err != chring.ErrNotFound {
+		t.Errorf("got error %v, want %v", err, chring.ErrNotFound)
+	}
+}
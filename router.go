@@ -0,0 +1,22 @@
+package chring
+
+// Router is the lookup surface shared by the ring implementations in this package, so that code can switch
+// between consistent hashing algorithms without changing call sites
+type Router interface {
+	// Get retrieves the node responsible for the given key, or "" if there are no nodes
+	Get(key string) string
+	// Add inserts a node, doing nothing if it is already present
+	Add(id string)
+	// Remove takes a node out, returning ErrNotFound if it is not present
+	Remove(id string) error
+	// Nodes returns the ID of every node
+	Nodes() []string
+}
+
+var (
+	_ Router = (*Ring)(nil)
+	_ Router = (*Ring64)(nil)
+	_ Router = (*JumpRing)(nil)
+	_ Router = (*RendezvousRing)(nil)
+	_ Router = (*MaglevTable)(nil)
+)
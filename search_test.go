@@ -39,3 +39,47 @@ func TestSearchFuncs(t *testing.T) {
 		}
 	}
 }
+
+func TestSearchFuncs64(t *testing.T) {
+	r := NewRing64()
+	for _, id := range []string{"Bar", "Raz", "Qux", "Foo"} {
+		r.Add(id)
+	}
+
+	for i, n := range r.nodes {
+		if got := r.findNode(n.ID); got != i {
+			t.Errorf("findNode: got [%d] for %q, want [%d]", got, n.ID, i)
+		}
+		if got := r.searchByHashID(r.Hasher(n.ID)); got != i {
+			t.Errorf("searchByHashID: got [%d] for %q (#%d), want [%d]", got, n.ID, r.Hasher(n.ID), i)
+		}
+	}
+	if got := r.findNode("Baz"); got != -1 {
+		t.Errorf("findNode: got [%d] for a missing node, want [-1]", got)
+	}
+}
+
+func TestVirtualNodeCounts(t *testing.T) {
+	r := NewRing(WithReplicas(100))
+	r.Add("a")
+	r.AddWithReplicas("b", 10)
+	r.AddWithReplicas("b", 10)
+	_ = r.AddWeighted("c", 4)
+	_ = r.AddAt("d", 1, 2, 3)
+	if got, want := r.current().nodes.Len(), 100+10+400+3; got != want {
+		t.Errorf("got %d virtual nodes, want %d", got, want)
+	}
+
+	_ = r.SetWeight("c", 0.5)
+	_ = r.Remove("a")
+	if got, want := r.current().nodes.Len(), 10+50+3; got != want {
+		t.Errorf("got %d virtual nodes after reweighting and removing, want %d", got, want)
+	}
+
+	r64 := NewRing64(WithReplicas(100))
+	r64.Add("a")
+	_ = r64.AddWeighted("b", 2)
+	if got, want := r64.nodes.Len(), 300; got != want {
+		t.Errorf("got %d 64 bit virtual nodes, want %d", got, want)
+	}
+}
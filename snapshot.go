@@ -0,0 +1,158 @@
+package chring
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"sort"
+)
+
+// RingView is an immutable snapshot of a Ring. Every change to the ring publishes a new view with a higher epoch,
+// so a client can pin the view it routed a request with for the duration of an operation, and later tell whether
+// the ring has changed since. The checksum covers everything that decides where a key goes, namely the ring's layout,
+// hasher, probe count and node states, but not how the ring got there, so two processes can compare checksums to know
+// whether they route keys the same way. Nothing reachable from a view is
+// ever modified, which is what lets lookups read it without locking.
+type RingView struct {
+	epoch    uint64
+	checksum uint32
+	nodes    nodes
+	size     int // number of distinct nodes
+	states   map[string]NodeState
+	hasher   func(id string) uint32
+	probes   int
+}
+
+// Snapshot returns the ring's current view
+func (r *Ring) Snapshot() *RingView {
+	return r.current()
+}
+
+// current returns the view that lookups should read
+func (r *Ring) current() *RingView {
+	return r.state.Load()
+}
+
+// publish makes the sorted nodes the ring's current view and returns the view it replaced. Callers must hold the
+// lock and must not modify the nodes afterwards.
+func (r *Ring) publish(ns nodes) *RingView {
+	r.epoch++
+	return r.state.Swap(&RingView{
+		epoch:    r.epoch,
+		checksum: checksum(ns, r.hasherName(), r.probes, r.states),
+		nodes:    ns,
+		size:     len(r.members),
+		states:   r.states,
+		hasher:   r.Hasher,
+		probes:   r.probes,
+	})
+}
+
+// Epoch numbers the views of a ring, starting at 1 for the empty ring and increasing with every change
+func (v *RingView) Epoch() uint64 {
+	return v.epoch
+}
+
+// Checksum is a CRC32 of the hasher's name, the probe count, every virtual node's position and node ID in ring order,
+// and the state of every node that is not active
+func (v *RingView) Checksum() uint32 {
+	return v.checksum
+}
+
+// Get retrieves the closest node in the view for the given key
+func (v *RingView) Get(key string) string {
+	n := v.lookup(key)
+	if n == nil {
+		return "" // should error?
+	}
+	return n.ID
+}
+
+// lookup returns the closest virtual node in the view for the given key that is not down, or nil if there is none
+func (v *RingView) lookup(key string) *node {
+	if len(v.nodes) == 0 {
+		return nil
+	}
+
+	i := v.locate(key)
+	if i >= v.nodes.Len() || i == -1 {
+		i = 0 // default to initial node
+	}
+	return v.owner(i)
+}
+
+// GetN retrieves the n distinct nodes that follow the given key clockwise in the view, closest first
+func (v *RingView) GetN(key string, n int) ([]string, error) {
+	found, err := v.successors(key, n)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(found))
+	for i, nd := range found {
+		ids[i] = nd.ID
+	}
+	return ids, nil
+}
+
+// successors returns the first virtual node of each of the n distinct nodes that follow the key clockwise,
+// skipping nodes that are down
+func (v *RingView) successors(key string, n int) ([]*node, error) {
+	if n < 0 {
+		return nil, ErrNegativeCount
+	}
+	if n > v.available() {
+		return nil, ErrNotEnoughNodes
+	}
+
+	found := make([]*node, 0, n)
+	seen := make(map[string]bool, n)
+	v.nodes.walk(v.locate(key), func(nd *node) bool {
+		if len(found) == n {
+			return false
+		}
+		if !seen[nd.ID] && v.states[nd.ID] != Down {
+			seen[nd.ID] = true
+			found = append(found, nd)
+		}
+		return true
+	})
+	return found, nil
+}
+
+// Nodes returns the ID of every node in the view once, in ring order
+func (v *RingView) Nodes() []string {
+	return v.nodes.ids()
+}
+
+// search finds the next node in the view for a given key
+func (v *RingView) search(key string) int {
+	return v.nodes.after(v.hasher(key))
+}
+
+// checksum hashes what decides the routing of a ring: its hasher and probes, its sorted virtual nodes, and the
+// states of its nodes
+func checksum(ns nodes, hasher string, probes int, states map[string]NodeState) uint32 {
+	h := crc32.NewIEEE()
+	var buf [4]byte
+	h.Write([]byte(hasher))
+	h.Write([]byte{0})
+	binary.BigEndian.PutUint32(buf[:], uint32(probes))
+	h.Write(buf[:])
+	for _, n := range ns {
+		binary.BigEndian.PutUint32(buf[:], n.HashID)
+		h.Write(buf[:])
+		h.Write([]byte(n.ID))
+		h.Write([]byte{0})
+	}
+
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte{0, byte(states[id])})
+	}
+	return h.Sum32()
+}
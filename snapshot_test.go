@@ -0,0 +1,79 @@
+package chring_test
+
+import (
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestSnapshotEpochs(t *testing.T) {
+	ring := chring.NewRing()
+	empty := ring.Snapshot()
+	if got := empty.Epoch(); got != 1 {
+		t.Errorf("got epoch %d, want 1 for a new ring", got)
+	}
+
+	ring.Add("node 1")
+	first := ring.Snapshot()
+	if first.Epoch() <= empty.Epoch() {
+		t.Errorf("got epoch %d after adding a node, want more than %d", first.Epoch(), empty.Epoch())
+	}
+	ring.Add("node 1") // no change, no new view
+	if got := ring.Snapshot().Epoch(); got != first.Epoch() {
+		t.Errorf("got epoch %d after a no-op add, want %d", got, first.Epoch())
+	}
+
+	ring.Add("node 2")
+	_ = ring.Remove("node 1")
+	if got := ring.Snapshot().Epoch(); got != first.Epoch()+2 {
+		t.Errorf("got epoch %d, want %d after two more changes", got, first.Epoch()+2)
+	}
+
+	// a pinned view keeps routing the way it did when it was taken
+	if got := first.Get("user A"); got != "node 1" {
+		t.Errorf("got %q from the pinned view, want %q", got, "node 1")
+	}
+	if got := first.Nodes(); len(got) != 1 || got[0] != "node 1" {
+		t.Errorf("got nodes %v from the pinned view, want [node 1]", got)
+	}
+	if got := ring.Get("user A"); got != "node 2" {
+		t.Errorf("got %q from the ring, want %q", got, "node 2")
+	}
+}
+
+func TestSnapshotChecksums(t *testing.T) {
+	a := chring.NewRing(chring.WithReplicas(10))
+	b := chring.NewRing(chring.WithReplicas(10))
+	for i := range NodeList {
+		a.Add(NodeList[i])
+		b.Add(NodeList[len(NodeList)-1-i])
+	}
+	b.Add("node x")
+	_ = b.Remove("node x")
+
+	if a.Snapshot().Checksum() != b.Snapshot().Checksum() {
+		t.Errorf("got checksums %d and %d, want rings with the same layout to match", a.Snapshot().Checksum(), b.Snapshot().Checksum())
+	}
+	if a.Snapshot().Epoch() == b.Snapshot().Epoch() {
+		t.Errorf("got epoch %d for both rings, want them to differ as they took different paths", a.Snapshot().Epoch())
+	}
+
+	before := a.Snapshot().Checksum()
+	_ = a.SetWeight("node 1", 2)
+	if a.Snapshot().Checksum() == before {
+		t.Error("got the same checksum after reweighting a node, want it to change")
+	}
+
+	// rings with the same nodes route keys differently with other hashers or probe counts
+	plain := chring.NewRing()
+	for _, opt := range []chring.Option{chring.WithProbes(21), chring.WithHasher(chring.FNV1a)} {
+		other := chring.NewRing(opt)
+		for _, n := range NodeList {
+			plain.Add(n)
+			other.Add(n)
+		}
+		if other.Snapshot().Checksum() == plain.Snapshot().Checksum() {
+			t.Error("got the same checksum for rings that route keys differently")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package chring
+
+import "errors"
+
+// NodeState tells whether a node is taking keys
+type NodeState int
+
+const (
+	// Active nodes serve and take keys. Nodes are active when added.
+	Active NodeState = iota
+	// Draining nodes keep serving the keys they have, but a RingManager places no new keys on them
+	Draining
+	// Down nodes are skipped by lookups, which go to the next node clockwise instead
+	Down
+)
+
+// String returns the name of the state
+func (s NodeState) String() string {
+	switch s {
+	case Active:
+		return "active"
+	case Draining:
+		return "draining"
+	case Down:
+		return "down"
+	}
+	return "unknown"
+}
+
+// ErrInvalidState is returned when setting a node to a state that does not exist
+var ErrInvalidState = errors.New("invalid node state")
+
+// SetState changes the state of a node. Unlike Remove, this never changes the layout of the ring: a node that is
+// down keeps its positions, and its keys go back to it as soon as it is active again.
+func (r *Ring) SetState(id string, s NodeState) error {
+	if s < Active || s > Down {
+		return ErrInvalidState
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.members[id]; !ok {
+		return ErrNotFound
+	}
+	if r.states[id] == s {
+		return nil
+	}
+	r.setState(id, s)
+	old := r.publish(r.current().nodes)
+	r.notify(StateChanged, id, old)
+	return nil
+}
+
+// State returns the state of the given node
+func (r *Ring) State(id string) (NodeState, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.members[id]; !ok {
+		return Active, ErrNotFound
+	}
+	return r.states[id], nil
+}
+
+// setState records the state of a node in a new map, as published views keep the old one.
+// Callers must hold the lock and publish a view afterwards.
+func (r *Ring) setState(id string, s NodeState) {
+	states := make(map[string]NodeState, len(r.states)+1)
+	for k, v := range r.states {
+		states[k] = v
+	}
+	if s == Active {
+		delete(states, id)
+	} else {
+		states[id] = s
+	}
+	r.states = states
+}
+
+// State returns the state of the given node in the view. Nodes that are not in the view are reported as active.
+func (v *RingView) State(id string) NodeState {
+	return v.states[id]
+}
+
+// available is the number of distinct nodes in the view that are not down
+func (v *RingView) available() int {
+	n := v.size
+	for _, s := range v.states {
+		if s == Down {
+			n--
+		}
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package chring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestDownNodesAreSkipped(t *testing.T) {
+	ring := chring.NewRing(chring.WithReplicas(20))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	before := ring.Snapshot()
+	events, cancel := ring.Subscribe()
+	defer cancel()
+
+	if err := ring.SetState("node 1", chring.Down); err != nil {
+		t.Fatal(err)
+	}
+	after := ring.Snapshot()
+	migrations := ring.Diff(before, after)
+	if len(migrations) == 0 {
+		t.Error("got no migrations after setting a node down, want its ranges to move")
+	}
+	for _, m := range migrations {
+		if m.From != "node 1" || m.To == "node 1" {
+			t.Errorf("got migration %+v, want only ranges moving off the down node", m)
+		}
+	}
+	if event := <-events; len(event.Migrations) != len(migrations) {
+		t.Errorf("got %s event with %d migrations, want the %d of Diff", event.Type, len(event.Migrations), len(migrations))
+	}
+	if ranges := after.Ranges("node 1"); len(ranges) != 0 {
+		t.Errorf("got %d ranges for a down node, want none", len(ranges))
+	}
+	if after.Checksum() == before.Checksum() {
+		t.Error("got the same checksum after setting a node down, want it to change as keys route differently")
+	}
+	if got := after.State("node 1"); got != chring.Down {
+		t.Errorf("got state %s, want %s", got, chring.Down)
+	}
+
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key %d", i)
+		got, want := ring.Get(key), before.Get(key)
+		if got == "node 1" {
+			t.Fatalf("got down node for %q", key)
+		}
+		if owner := after.OwnerOf(chring.DefaultHasher(key)); owner != got {
+			t.Errorf("got owner %q for the hash of %q, want %q to match Get", owner, key, got)
+		}
+		if want == "node 1" {
+			moved++
+			continue
+		}
+		if got != want {
+			t.Errorf("got %q for %q, want it to stay on %q", got, key, want)
+		}
+	}
+	if moved == 0 {
+		t.Error("got no keys from the down node, want some to move")
+	}
+
+	found, err := ring.GetN("user A", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range found {
+		if id == "node 1" {
+			t.Errorf("got down node in GetN %v", found)
+		}
+	}
+	if _, err := ring.GetN("user A", 4); err != chring.ErrNotEnoughNodes {
+		t.Errorf("got error %v for every node including the down one, want %v", err, chring.ErrNotEnoughNodes)
+	}
+
+	if err := ring.SetState("node 1", chring.Active); err != nil {
+		t.Fatal(err)
+	}
+	if back := ring.Diff(after, ring.Snapshot()); len(back) != len(migrations) {
+		t.Errorf("got %d migrations once the node was back, want the %d that moved off it", len(back), len(migrations))
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key %d", i)
+		if got, want := ring.Get(key), before.Get(key); got != want {
+			t.Errorf("got %q for %q once the node was back, want %q", got, key, want)
+		}
+	}
+}
+
+func TestSetState(t *testing.T) {
+	ring := newSeededRing()
+	events, cancel := ring.Subscribe()
+	defer cancel()
+
+	if err := ring.SetState("node 2", chring.Draining); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := ring.State("node 2"); s != chring.Draining {
+		t.Errorf("got state %s, want %s", s, chring.Draining)
+	}
+	if event := <-events; event.Type != chring.StateChanged || len(event.Migrations) != 0 {
+		t.Errorf("got %s event with %d migrations, want %s with none", event.Type, len(event.Migrations), chring.StateChanged)
+	}
+
+	if err := ring.SetState("node 9", chring.Down); err != chring.ErrNotFound {
+		t.Errorf("got error %v for a missing node, want %v", err, chring.ErrNotFound)
+	}
+	if err := ring.SetState("node 2", chring.NodeState(7)); err != chring.ErrInvalidState {
+		t.Errorf("got error %v for an unknown state, want %v", err, chring.ErrInvalidState)
+	}
+
+	// removing a node forgets its state
+	_ = ring.SetState("node 3", chring.Down)
+	_ = ring.Remove("node 3")
+	ring.Add("node 3")
+	if s, _ := ring.State("node 3"); s != chring.Active {
+		t.Errorf("got state %s for a re-added node, want %s", s, chring.Active)
+	}
+}
@@ -0,0 +1,98 @@
+package chring
+
+import (
+	"math"
+)
+
+// Stats describes how the hash space of a ring, or the keys of a ring manager, are spread over the nodes
+type Stats struct {
+	// Ownership is the share of the hash space owned by each node, adding up to 1
+	Ownership map[string]float64
+	// Keys is the number of keys held by each node. It is only filled in by RingManager.Stats.
+	Keys map[string]int
+	// StdDev is the standard deviation of the per node ownership, or of the per node key counts for a ring manager
+	StdDev float64
+	// MaxMeanRatio is the largest per node ownership or key count over the mean. It is 1 for a perfectly balanced
+	// ring and grows with the imbalance, which makes it a good value to alarm on.
+	MaxMeanRatio float64
+}
+
+// Stats reports the share of the hash space owned by each node in the ring
+func (r *Ring) Stats() Stats {
+	ownership := ownership(r.current().nodes, false)
+	values := make([]float64, 0, len(ownership))
+	for _, share := range ownership {
+		values = append(values, share)
+	}
+	stats := Stats{Ownership: ownership}
+	stats.StdDev, stats.MaxMeanRatio = spread(values)
+	return stats
+}
+
+// Stats reports the number of keys held by each node, along with the share of the hash space each node owns
+func (rm *RingManager) Stats() (Stats, error) {
+	stats := Stats{
+		Ownership: ownership(rm.nodeRing.current().nodes, true),
+		Keys:      make(map[string]int),
+	}
+	var values []float64
+	for _, id := range rm.GetNodes() {
+		keys, err := rm.GetKeys(id)
+		if err != nil {
+			return Stats{}, err
+		}
+		stats.Keys[id] = len(keys)
+		values = append(values, float64(len(keys)))
+	}
+	stats.StdDev, stats.MaxMeanRatio = spread(values)
+	return stats, nil
+}
+
+// ownership returns the share of the hash space owned by each node. In a Ring every virtual node owns the gap up to
+// its position, while in a ring manager it owns the keys after its position, up to the next virtual node.
+func ownership[H position](ns points[H], keysAfter bool) map[string]float64 {
+	shares := make(map[string]float64)
+	if len(ns) == 0 {
+		return shares
+	}
+	space := float64(^H(0)) + 1
+	for i, n := range ns {
+		prev := ns[(i+len(ns)-1)%len(ns)]
+		// unsigned subtraction wraps around the end of the hash space
+		gap := float64(n.HashID - prev.HashID)
+		if len(ns) == 1 {
+			gap = space
+		}
+		owner := n.ID
+		if keysAfter {
+			owner = prev.ID
+		}
+		shares[owner] += gap / space
+	}
+	return shares
+}
+
+// spread returns the standard deviation and the max over mean ratio of the values
+func spread(values []float64) (stdDev, maxMean float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum, max float64
+	for _, v := range values {
+		sum += v
+		if v > max {
+			max = v
+		}
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	stdDev = math.Sqrt(squares / float64(len(values)))
+	if mean > 0 {
+		maxMean = max / mean
+	}
+	return stdDev, maxMean
+}
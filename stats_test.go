@@ -0,0 +1,74 @@
+package chring_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+func TestRingStats(t *testing.T) {
+	ring := chring.NewRing()
+	_ = ring.AddAt("a", 1<<30)
+	_ = ring.AddAt("b", 1<<31)
+
+	stats := ring.Stats()
+	if got := stats.Ownership["b"]; got != 0.25 {
+		t.Errorf("got ownership %v for b, want 0.25", got)
+	}
+	if got := stats.Ownership["a"]; got != 0.75 {
+		t.Errorf("got ownership %v for a, want 0.75", got)
+	}
+	if got := stats.MaxMeanRatio; got != 1.5 {
+		t.Errorf("got max/mean ratio %v, want 1.5", got)
+	}
+	if got := stats.StdDev; got != 0.25 {
+		t.Errorf("got standard deviation %v, want 0.25", got)
+	}
+
+	ring = chring.NewRing(chring.WithReplicas(200))
+	for _, n := range NodeList {
+		ring.Add(n)
+	}
+	stats = ring.Stats()
+	var total float64
+	for _, share := range stats.Ownership {
+		total += share
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("got ownership adding up to %v, want 1", total)
+	}
+	if stats.MaxMeanRatio > 1.2 {
+		t.Errorf("got max/mean ratio %v with 200 virtual nodes, want a balanced ring", stats.MaxMeanRatio)
+	}
+}
+
+func TestManagerStats(t *testing.T) {
+	ringManager := chring.NewRingManager()
+	_ = ringManager.AddNode("node a")
+	_ = ringManager.AddNode("node b")
+	for i := 1; i <= 100; i++ {
+		_ = ringManager.AddKey(fmt.Sprintf("user_%d", i))
+	}
+
+	stats, err := ringManager.Stats()
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	keysInA, _ := ringManager.GetKeys("node a")
+	if got, want := stats.Keys["node a"], len(keysInA); got != want {
+		t.Errorf("got %d keys for node a, want %d", got, want)
+	}
+	if got := stats.Keys["node a"] + stats.Keys["node b"]; got != 100 {
+		t.Errorf("got %d keys in total, want 100", got)
+	}
+	mean := 50.0
+	max := math.Max(float64(stats.Keys["node a"]), float64(stats.Keys["node b"]))
+	if got, want := stats.MaxMeanRatio, max/mean; got != want {
+		t.Errorf("got max/mean ratio %v, want %v", got, want)
+	}
+	if got, want := stats.StdDev, max-mean; math.Abs(got-want) > 1e-9 {
+		t.Errorf("got standard deviation %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,402 @@
+package chring
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultCheckpointEvery is how many changes a Store logs before it checkpoints the ring
+const DefaultCheckpointEvery = 1000
+
+// file names within a store's directory
+const (
+	checkpointFile = "checkpoint"
+	walFile        = "wal"
+)
+
+// Store keeps a Ring on disk so that it survives restarts. Every change made through the store is appended to a
+// write-ahead log and synced before it is applied to the ring, and every CheckpointEvery changes the whole ring is
+// written to a checkpoint and the log starts over. Checkpoints and log records carry checksums, so a damaged store
+// is reported rather than loaded. Read the ring through Ring, but change it through the store: changes made to the
+// ring directly are only saved by the next checkpoint.
+type Store struct {
+	sync.Mutex
+	// CheckpointEvery is how many changes are logged between checkpoints. Set it before making changes.
+	CheckpointEvery int
+
+	ring    *Ring
+	dir     string
+	wal     *os.File
+	seq     uint64 // sequence number of the last change
+	pending int    // changes logged since the last checkpoint
+	size    int64  // length of the log up to the end of its last good record
+}
+
+// walHeaderSize is the length of a log record's header: the payload length, a checksum of the length, and a
+// checksum of the payload. The length has its own checksum so that a damaged length is told apart from a record
+// cut short at the end of the log.
+const walHeaderSize = 12
+
+// walRecord is a change to the ring as it is logged
+type walRecord struct {
+	Seq       uint64            `json:"seq"`
+	Op        string            `json:"op"`
+	ID        string            `json:"id"`
+	Replicas  int               `json:"replicas,omitempty"`
+	Weight    float64           `json:"weight,omitempty"`
+	Positions []uint32          `json:"positions,omitempty"`
+	Addr      string            `json:"addr,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	State     NodeState         `json:"state,omitempty"`
+}
+
+// logged operations
+const (
+	opAdd       = "add"
+	opWeighted  = "addWeighted"
+	opAddAt     = "addAt"
+	opAddNode   = "addNode"
+	opSetWeight = "setWeight"
+	opSetState  = "setState"
+	opRemove    = "remove"
+)
+
+// OpenStore opens the store in the directory, creating both if needed, and recovers the ring from the last
+// checkpoint and the changes logged since. The options configure the ring as they do for NewRing; the hasher must
+// be the one the store was created with, or ErrHasherMismatch is returned. A record cut short at the end of the log,
+// as left by a crash while writing it, is dropped; any other damage returns ErrCorrupt.
+func OpenStore(dir string, opts ...Option) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &Store{CheckpointEvery: DefaultCheckpointEvery, ring: NewRing(opts...), dir: dir}
+	if err := s.loadCheckpoint(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.replay(wal); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	s.wal = wal
+	return s, nil
+}
+
+// Ring returns the stored ring for lookups
+func (s *Store) Ring() *Ring {
+	return s.ring
+}
+
+// Add inserts a new node into the ring, see Ring.Add
+func (s *Store) Add(id string) error {
+	return s.log(walRecord{Op: opAdd, ID: id, Replicas: s.ring.replicas})
+}
+
+// AddWithReplicas inserts a new node into the ring at n virtual positions, see Ring.AddWithReplicas
+func (s *Store) AddWithReplicas(id string, n int) error {
+	return s.log(walRecord{Op: opAdd, ID: id, Replicas: n})
+}
+
+// AddWeighted inserts a new weighted node into the ring, see Ring.AddWeighted
+func (s *Store) AddWeighted(id string, weight float64) error {
+	return s.log(walRecord{Op: opWeighted, ID: id, Weight: weight})
+}
+
+// AddAt pins a node at the given hash positions, see Ring.AddAt
+func (s *Store) AddAt(id string, hashIDs ...uint32) error {
+	return s.log(walRecord{Op: opAddAt, ID: id, Positions: hashIDs})
+}
+
+// AddNode inserts a new node with its address and tags into the ring, see Ring.AddNode
+func (s *Store) AddNode(m Member) error {
+	return s.log(walRecord{Op: opAddNode, ID: m.ID, Addr: m.Addr, Tags: m.Tags})
+}
+
+// SetWeight changes the weight of a node, see Ring.SetWeight
+func (s *Store) SetWeight(id string, weight float64) error {
+	return s.log(walRecord{Op: opSetWeight, ID: id, Weight: weight})
+}
+
+// SetState changes the state of a node, see Ring.SetState
+func (s *Store) SetState(id string, state NodeState) error {
+	return s.log(walRecord{Op: opSetState, ID: id, State: state})
+}
+
+// Remove takes the node out of the ring, see Ring.Remove
+func (s *Store) Remove(id string) error {
+	return s.log(walRecord{Op: opRemove, ID: id})
+}
+
+// Checkpoint writes the whole ring to disk and empties the log
+func (s *Store) Checkpoint() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.checkpoint()
+}
+
+// Close checkpoints the ring and closes the log. The ring stays usable for lookups.
+func (s *Store) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	err := s.checkpoint()
+	if cerr := s.wal.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// log appends the change to the log, syncs it and applies it to the ring. A change that check rejects is returned
+// without being logged. Any other change that the ring rejects is logged all the same; it is rejected again when the
+// log is replayed.
+func (s *Store) log(rec walRecord) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.check(rec); err != nil {
+		return err
+	}
+	rec.Seq = s.seq + 1
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(frame))
+	frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(payload))
+	frame = append(frame, payload...)
+	if _, err := s.wal.Write(frame); err != nil {
+		return s.rollback(err)
+	}
+	if err := s.wal.Sync(); err != nil {
+		return s.rollback(err)
+	}
+	s.size += int64(len(frame))
+	s.seq = rec.Seq
+	s.pending++
+
+	if err := s.apply(rec); err != nil {
+		return err
+	}
+	if s.pending >= s.CheckpointEvery {
+		return s.checkpoint()
+	}
+	return nil
+}
+
+// rollback cuts a record that failed to be written off the end of the log, so that the next record follows the
+// last good one, and returns the write error, or the error that kept the log from being rolled back. Callers must
+// hold the lock.
+func (s *Store) rollback(err error) error {
+	if terr := s.wal.Truncate(s.size); terr != nil {
+		return err
+	}
+	if _, serr := s.wal.Seek(s.size, io.SeekStart); serr != nil {
+		return serr
+	}
+	return err
+}
+
+// check returns the error the ring would give for the change, where that is cheap to tell, so that changes bound
+// to fail are never logged. Callers must hold the lock.
+func (s *Store) check(rec walRecord) error {
+	switch rec.Op {
+	case opWeighted:
+		if !validWeight(rec.Weight) {
+			return ErrInvalidWeight
+		}
+	case opAddAt:
+		if len(rec.Positions) == 0 {
+			return ErrNoPositions
+		}
+		current := s.ring.current().nodes
+		seen := make(map[uint32]bool, len(rec.Positions))
+		for _, hashID := range rec.Positions {
+			if seen[hashID] || current.occupied(hashID) {
+				return ErrCollision
+			}
+			seen[hashID] = true
+		}
+	case opSetWeight:
+		if !validWeight(rec.Weight) {
+			return ErrInvalidWeight
+		}
+		_, err := s.ring.State(rec.ID)
+		return err
+	case opSetState:
+		if rec.State < Active || rec.State > Down {
+			return ErrInvalidState
+		}
+		_, err := s.ring.State(rec.ID)
+		return err
+	case opRemove:
+		_, err := s.ring.State(rec.ID)
+		return err
+	}
+	return nil
+}
+
+// apply makes a logged change to the ring
+func (s *Store) apply(rec walRecord) error {
+	switch rec.Op {
+	case opAdd:
+		s.ring.AddWithReplicas(rec.ID, rec.Replicas)
+	case opWeighted:
+		return s.ring.AddWeighted(rec.ID, rec.Weight)
+	case opAddAt:
+		return s.ring.AddAt(rec.ID, rec.Positions...)
+	case opAddNode:
+		s.ring.AddNode(Member{ID: rec.ID, Addr: rec.Addr, Tags: rec.Tags})
+	case opSetWeight:
+		return s.ring.SetWeight(rec.ID, rec.Weight)
+	case opSetState:
+		return s.ring.SetState(rec.ID, rec.State)
+	case opRemove:
+		return s.ring.Remove(rec.ID)
+	default:
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// checkpoint writes the ring and the sequence number of its last change to a new file that replaces the old
+// checkpoint, then empties the log. A crash in between leaves changes in the log that the checkpoint already has,
+// which replay skips by their sequence numbers. Callers must hold the lock.
+func (s *Store) checkpoint() error {
+	ring, err := s.ring.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	data := binary.BigEndian.AppendUint64(nil, s.seq)
+	data = append(data, ring...)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+
+	tmp := filepath.Join(s.dir, checkpointFile+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, checkpointFile)); err != nil {
+		return err
+	}
+	// the rename must be on disk before the log is emptied, or a crash could lose both
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	if err := s.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.pending = 0
+	s.size = 0
+	return s.wal.Sync()
+}
+
+// syncDir flushes the directory's entries to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// loadCheckpoint restores the ring from the checkpoint, if there is one
+func (s *Store) loadCheckpoint() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, checkpointFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(data) < 8+4 {
+		return fmt.Errorf("%w: checkpoint is too short", ErrCorrupt)
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return fmt.Errorf("%w: checkpoint checksum does not match", ErrCorrupt)
+	}
+	s.seq = binary.BigEndian.Uint64(body)
+	return s.ring.UnmarshalBinary(body[8:])
+}
+
+// replay applies the changes logged after the checkpoint and leaves the log positioned for appending. A record is
+// only dropped when it runs up to the end of the log, as a crash while appending leaves no more than one such record;
+// damage anywhere else returns ErrCorrupt.
+func (s *Store) replay(wal *os.File) error {
+	data, err := io.ReadAll(wal)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for offset < len(data) {
+		start, rest := offset, data[offset:]
+		if len(rest) < walHeaderSize {
+			break // header cut short
+		}
+		if crc32.ChecksumIEEE(rest[:4]) != binary.BigEndian.Uint32(rest[4:]) {
+			return fmt.Errorf("%w: log record at offset %d has a damaged length", ErrCorrupt, start)
+		}
+		size := int(binary.BigEndian.Uint32(rest))
+		if len(rest)-walHeaderSize < size {
+			break // payload cut short
+		}
+		end := walHeaderSize + size
+		payload := rest[walHeaderSize:end]
+		var rec walRecord
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(rest[8:]) || json.Unmarshal(payload, &rec) != nil {
+			if offset+end < len(data) {
+				return fmt.Errorf("%w: log record at offset %d", ErrCorrupt, start)
+			}
+			break
+		}
+		offset += end
+
+		if rec.Seq <= s.seq {
+			continue // already in the checkpoint
+		}
+		if rec.Seq != s.seq+1 {
+			return fmt.Errorf("%w: log record at offset %d skips changes", ErrCorrupt, start)
+		}
+		s.seq = rec.Seq
+		s.pending++
+		if err := s.apply(rec); err == ErrCorrupt {
+			return fmt.Errorf("%w: log record at offset %d has an unknown change", ErrCorrupt, start)
+		}
+	}
+
+	if err := wal.Truncate(int64(offset)); err != nil {
+		return err
+	}
+	s.size = int64(offset)
+	_, err = wal.Seek(s.size, io.SeekStart)
+	return err
+}
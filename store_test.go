@@ -0,0 +1,222 @@
+package chring_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+// fillStore makes one of each kind of change through the store
+func fillStore(t *testing.T, s *chring.Store) {
+	t.Helper()
+	for _, n := range NodeList {
+		if err := s.Add(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	steps := []error{
+		s.AddWeighted("big", 3),
+		s.AddAt("pinned", 42),
+		s.AddNode(chring.Member{ID: "cache", Addr: "10.0.0.1:6379", Tags: map[string]string{"zone": "a"}}),
+		s.SetWeight("node 1", 2),
+		s.SetState("node 2", chring.Down),
+		s.Remove("node 3"),
+		s.AddWithReplicas("node 5", 7),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStoreRecovers(t *testing.T) {
+	dir := t.TempDir()
+	s, err := chring.OpenStore(dir, chring.WithReplicas(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fillStore(t, s)
+	want := s.Ring()
+
+	// reopen without closing, as after a crash, so everything comes from the log
+	recovered, err := chring.OpenStore(dir, chring.WithReplicas(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameRing(t, recovered.Ring(), want)
+
+	// changes keep going to the log after recovery
+	if err := recovered.Remove("node 4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := recovered.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := chring.OpenStore(dir, chring.WithReplicas(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	assertSameRing(t, reopened.Ring(), recovered.Ring())
+	if got, want := reopened.Ring().Snapshot().Epoch(), recovered.Ring().Snapshot().Epoch(); got != want {
+		t.Errorf("got epoch %d from the checkpoint, want %d", got, want)
+	}
+}
+
+func TestStoreCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	s, err := chring.OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CheckpointEvery = 3
+	fillStore(t, s) // 11 changes: 3 checkpoints and 2 changes in the log
+
+	if _, err := os.Stat(filepath.Join(dir, "checkpoint")); err != nil {
+		t.Fatalf("got no checkpoint: %v", err)
+	}
+	recovered, err := chring.OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameRing(t, recovered.Ring(), s.Ring())
+}
+
+func TestStoreRejectsChangesBeforeLogging(t *testing.T) {
+	dir := t.TempDir()
+	s, err := chring.OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.AddAt("pinned", 42); err != nil {
+		t.Fatal(err)
+	}
+
+	wal := filepath.Join(dir, "wal")
+	before, _ := os.ReadFile(wal)
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"negative weight", s.AddWeighted("a", -1), chring.ErrInvalidWeight},
+		{"zero weight", s.SetWeight("pinned", 0), chring.ErrInvalidWeight},
+		{"unknown node weight", s.SetWeight("node x", 2), chring.ErrNotFound},
+		{"unknown node state", s.SetState("node x", chring.Down), chring.ErrNotFound},
+		{"invalid state", s.SetState("pinned", chring.NodeState(7)), chring.ErrInvalidState},
+		{"unknown node removal", s.Remove("node x"), chring.ErrNotFound},
+		{"no positions", s.AddAt("b"), chring.ErrNoPositions},
+		{"taken position", s.AddAt("b", 42), chring.ErrCollision},
+	}
+	for _, test := range tests {
+		if test.err != test.want {
+			t.Errorf("%s: got error %v, want %v", test.name, test.err, test.want)
+		}
+	}
+	if after, _ := os.ReadFile(wal); !bytes.Equal(after, before) {
+		t.Errorf("got %d bytes of log after rejected changes, want the %d before them", len(after), len(before))
+	}
+}
+
+func TestStoreDropsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := chring.OpenStore(dir)
+	fillStore(t, s)
+
+	wal := filepath.Join(dir, "wal")
+	data, _ := os.ReadFile(wal)
+	torn := binary.BigEndian.AppendUint32(nil, 40)
+	torn = binary.BigEndian.AppendUint32(torn, crc32.ChecksumIEEE(torn))
+	torn = append(torn, 1, 2, 3, 4, 5, 6) // payload checksum and part of the payload
+	if err := os.WriteFile(wal, append(data, torn...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := chring.OpenStore(dir)
+	if err != nil {
+		t.Fatalf("got error %v for a record cut short, want it dropped", err)
+	}
+	assertSameRing(t, recovered.Ring(), s.Ring())
+
+	// the torn record is gone, so new records follow the good ones
+	_ = recovered.Add("node 9")
+	again, err := chring.OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameRing(t, again.Ring(), recovered.Ring())
+}
+
+func TestStoreDetectsCorruption(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := chring.OpenStore(dir)
+	fillStore(t, s)
+
+	wal := filepath.Join(dir, "wal")
+	data, _ := os.ReadFile(wal)
+	data[20] ^= 0xff // inside the payload of the first record
+	_ = os.WriteFile(wal, data, 0644)
+	if _, err := chring.OpenStore(dir); !errors.Is(err, chring.ErrCorrupt) {
+		t.Errorf("got error %v for a damaged log, want %v", err, chring.ErrCorrupt)
+	}
+
+	dir = t.TempDir()
+	s, _ = chring.OpenStore(dir)
+	fillStore(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint := filepath.Join(dir, "checkpoint")
+	data, _ = os.ReadFile(checkpoint)
+	data[len(data)/2] ^= 0xff
+	_ = os.WriteFile(checkpoint, data, 0644)
+	if _, err := chring.OpenStore(dir); !errors.Is(err, chring.ErrCorrupt) {
+		t.Errorf("got error %v for a damaged checkpoint, want %v", err, chring.ErrCorrupt)
+	}
+}
+
+func TestStoreDetectsDamagedLength(t *testing.T) {
+	for _, record := range []int{0, 2} {
+		dir := t.TempDir()
+		s, _ := chring.OpenStore(dir)
+		for i := 0; i < 5; i++ {
+			_ = s.Add(fmt.Sprintf("node %d", i))
+		}
+
+		// a length running past the end of the log must not pass for a record cut short
+		wal := filepath.Join(dir, "wal")
+		data, _ := os.ReadFile(wal)
+		offset := 0
+		for i := 0; i < record; i++ {
+			offset += 12 + int(binary.BigEndian.Uint32(data[offset:]))
+		}
+		binary.BigEndian.PutUint32(data[offset:], 0x00ffffff)
+		_ = os.WriteFile(wal, data, 0644)
+
+		if _, err := chring.OpenStore(dir); !errors.Is(err, chring.ErrCorrupt) {
+			t.Errorf("got error %v for a damaged length in record %d, want %v", err, record, chring.ErrCorrupt)
+		}
+		if after, _ := os.ReadFile(wal); len(after) != len(data) {
+			t.Errorf("got a log of %d bytes, want the damaged log of %d bytes left alone", len(after), len(data))
+		}
+	}
+}
+
+func TestStoreRejectsOtherHasher(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := chring.OpenStore(dir)
+	fillStore(t, s)
+	_ = s.Close()
+
+	if _, err := chring.OpenStore(dir, chring.WithHasher(chring.XXHash)); err != chring.ErrHasherMismatch {
+		t.Errorf("got error %v, want %v", err, chring.ErrHasherMismatch)
+	}
+}
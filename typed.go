@@ -0,0 +1,92 @@
+package chring
+
+// TypedRing is a consistent hash ring over values of any type, such as a *Backend, instead of node IDs. Each value
+// is placed in the ring under the ID its key function returns, and lookups return the value itself straight from the
+// ring, without a second lookup by ID. Everything else about the ring, like replicas, hashers and snapshots, works
+// as it does for the underlying Ring.
+type TypedRing[T any] struct {
+	ring *Ring
+	key  func(T) string
+}
+
+// NewTypedRing creates a ring of values that are identified by the given key function, configured with the same
+// options as NewRing
+func NewTypedRing[T any](key func(T) string, opts ...Option) *TypedRing[T] {
+	return &TypedRing[T]{ring: NewRing(opts...), key: key}
+}
+
+// Ring returns the underlying ring, keyed by the IDs of the values, for snapshots, statistics and change events
+func (r *TypedRing[T]) Ring() *Ring {
+	return r.ring
+}
+
+// Add inserts a new value into the hash ring using the ring's configured replica count
+func (r *TypedRing[T]) Add(v T) {
+	r.ring.Lock()
+	defer r.ring.Unlock()
+
+	r.ring.addMember(&member{ID: r.key(v), Replicas: r.ring.replicas, Weight: 1, value: v})
+}
+
+// AddWeighted inserts a new value into the hash ring owning roughly weight times the hash space of a value added with Add
+func (r *TypedRing[T]) AddWeighted(v T, weight float64) error {
+	if !validWeight(weight) {
+		return ErrInvalidWeight
+	}
+
+	r.ring.Lock()
+	defer r.ring.Unlock()
+
+	r.ring.addMember(&member{ID: r.key(v), Replicas: weightedReplicas(r.ring.replicas, weight), Weight: weight, value: v})
+	return nil
+}
+
+// Remove takes the value and all of its virtual nodes out of the hash ring
+func (r *TypedRing[T]) Remove(v T) error {
+	return r.ring.Remove(r.key(v))
+}
+
+// Get retrieves the closest value in the hash ring for the given key
+func (r *TypedRing[T]) Get(key string) (T, error) {
+	n := r.ring.current().lookup(key)
+	if n == nil {
+		var zero T
+		return zero, ErrNotFound
+	}
+	return r.value(n), nil
+}
+
+// GetN retrieves the n distinct values that follow the given key clockwise in the hash ring, closest first
+func (r *TypedRing[T]) GetN(key string, n int) ([]T, error) {
+	found, err := r.ring.current().successors(key, n)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]T, len(found))
+	for i, nd := range found {
+		values[i] = r.value(nd)
+	}
+	return values, nil
+}
+
+// Values returns every value in the ring once, in ring order
+func (r *TypedRing[T]) Values() []T {
+	v := r.ring.current()
+	values := make([]T, 0, v.size)
+	seen := make(map[string]bool, v.size)
+	for _, n := range v.nodes {
+		if !seen[n.ID] {
+			seen[n.ID] = true
+			values = append(values, r.value(n))
+		}
+	}
+	return values
+}
+
+// value returns the value a virtual node stands for. Nodes added to the underlying ring directly hold no value and
+// come back as the zero value.
+func (r *TypedRing[T]) value(n *node) T {
+	v, _ := n.member.value.(T)
+	return v
+}
@@ -0,0 +1,67 @@
+package chring_test
+
+import (
+	"testing"
+
+	"github.com/sethgrid/chring"
+)
+
+type backend struct {
+	Name string
+	Addr string
+}
+
+func TestTypedRing(t *testing.T) {
+	ring := chring.NewTypedRing(func(b *backend) string { return b.Name }, chring.WithReplicas(20))
+	if _, err := ring.Get("user A"); err != chring.ErrNotFound {
+		t.Errorf("got error %v for an empty ring, want %v", err, chring.ErrNotFound)
+	}
+
+	backends := map[string]*backend{}
+	for _, n := range NodeList {
+		b := &backend{Name: n, Addr: n + ":8080"}
+		backends[n] = b
+		ring.Add(b)
+	}
+	plain := chring.NewRing(chring.WithReplicas(20))
+	for _, n := range NodeList {
+		plain.Add(n)
+	}
+
+	for _, key := range []string{"user A", "user B", "user C", "user D"} {
+		b, err := ring.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := plain.Get(key); b != backends[want] {
+			t.Errorf("got %+v for %q, want %+v", b, key, backends[want])
+		}
+
+		found, err := ring.GetN(key, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids, _ := plain.GetN(key, 3)
+		for i := range found {
+			if found[i] != backends[ids[i]] {
+				t.Errorf("got %+v at replica %d of %q, want %+v", found[i], i, key, backends[ids[i]])
+			}
+		}
+	}
+
+	if err := ring.Remove(backends["node 1"]); err != nil {
+		t.Fatal(err)
+	}
+	values := ring.Values()
+	if len(values) != len(NodeList)-1 {
+		t.Errorf("got %d values, want %d", len(values), len(NodeList)-1)
+	}
+	for _, b := range values {
+		if b == backends["node 1"] {
+			t.Error("got removed backend in Values")
+		}
+	}
+	if got := ring.Ring().Nodes(); len(got) != len(values) {
+		t.Errorf("got %d nodes in the underlying ring, want %d", len(got), len(values))
+	}
+}